@@ -0,0 +1,143 @@
+package xxl
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+调度中心地址，ServerAddr可配置多个，逗号分隔，请求失败时自动切换到下一个地址
+*/
+
+// 调度中心地址选择策略
+const (
+	AdminRouteFailover   = "FAILOVER"    // 按配置顺序使用第一个可用地址
+	AdminRouteRoundRobin = "ROUND_ROBIN" // 在可用地址间轮询
+)
+
+// 地址请求失败后暂停使用的时间，连续失败时翻倍，最长adminMaxDownTime
+const (
+	adminDownTime    = 10 * time.Second
+	adminMaxDownTime = 5 * time.Minute
+)
+
+// 没有设置Timeout时请求调度中心的超时时间，避免调度中心不响应时Init、RegTask、Stop一直阻塞
+const defaultAdminTimeout = 10 * time.Second
+
+// adminTimeout 请求调度中心的超时时间，timeout小于等于0时使用defaultAdminTimeout
+func adminTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultAdminTimeout
+	}
+	return timeout
+}
+
+// splitAdminAddrs 拆分逗号分隔的调度中心地址
+func splitAdminAddrs(addrs string) []string {
+	var list []string
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimRight(strings.TrimSpace(addr), "/")
+		if addr != "" {
+			list = append(list, addr)
+		}
+	}
+	return list
+}
+
+// adminNode 调度中心地址及其健康状态
+type adminNode struct {
+	addr      string
+	failures  int       //连续失败次数
+	downUntil time.Time //在此之前视为不可用
+}
+
+// adminClient 调度中心地址选择
+type adminClient struct {
+	mu    sync.Mutex
+	nodes []*adminNode
+	route string
+	next  int
+}
+
+func newAdminClient(addrs, route string) *adminClient {
+	c := &adminClient{route: route}
+	for _, addr := range splitAdminAddrs(addrs) {
+		c.nodes = append(c.nodes, &adminNode{addr: addr})
+	}
+	return c
+}
+
+// candidates 本次请求依次尝试的地址，可用地址在前，不可用地址作为兜底
+func (c *adminClient) candidates() []*adminNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	healthy := make([]*adminNode, 0, len(c.nodes))
+	var down []*adminNode
+	for _, node := range c.nodes {
+		if now.Before(node.downUntil) {
+			down = append(down, node)
+		} else {
+			healthy = append(healthy, node)
+		}
+	}
+	if c.route == AdminRouteRoundRobin && len(healthy) > 1 {
+		start := c.next % len(healthy)
+		c.next++
+		rotated := make([]*adminNode, 0, len(healthy))
+		rotated = append(rotated, healthy[start:]...)
+		healthy = append(rotated, healthy[:start]...)
+	}
+	return append(healthy, down...)
+}
+
+func (c *adminClient) success(node *adminNode) {
+	c.mu.Lock()
+	node.failures = 0
+	node.downUntil = time.Time{}
+	c.mu.Unlock()
+}
+
+func (c *adminClient) failure(node *adminNode) {
+	c.mu.Lock()
+	node.failures++
+	d := adminDownTime << (node.failures - 1)
+	if d > adminMaxDownTime || d <= 0 {
+		d = adminMaxDownTime
+	}
+	node.downUntil = time.Now().Add(d)
+	c.mu.Unlock()
+}
+
+// do 依次请求各地址，网络错误或5xx时切换到下一个地址
+func (c *adminClient) do(newRequest func(addr string) (*http.Request, error), client *http.Client) (*http.Response, error) {
+	nodes := c.candidates()
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("xxl-job admin address is empty")
+	}
+	var lastErr error
+	for _, node := range nodes {
+		request, err := newRequest(node.addr)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(request)
+		if err != nil {
+			c.failure(node)
+			lastErr = fmt.Errorf("%s: %w", node.addr, err)
+			continue
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			resp.Body.Close()
+			c.failure(node)
+			lastErr = fmt.Errorf("%s: status %d", node.addr, resp.StatusCode)
+			continue
+		}
+		c.success(node)
+		return resp, nil
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,214 @@
+package xxl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+任务结果回调，后台批量发送，失败后重试，仍失败则写入LogDir/callbacklog，定时重新发送
+*/
+
+const (
+	callbackQueueSize     = 1000             //回调队列长度，队列满时直接落盘
+	callbackBatchSize     = 100              //单次回调最多包含的结果数
+	callbackRetryTimes    = 3                //单次发送失败后的重试次数
+	callbackRetryBackoff  = time.Second      //首次重试间隔，之后翻倍
+	callbackRetryInterval = 30 * time.Second //落盘回调的重试间隔
+	callbackFilePrefix    = "xxl-job-callback-"
+)
+
+// callbackSender 回调发送器
+type callbackSender struct {
+	dir  string
+	log  StructuredLogger
+	send func(list call) error
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan *callElement
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newCallbackSender(dir string, log StructuredLogger, send func(list call) error) *callbackSender {
+	return &callbackSender{
+		dir:    dir,
+		log:    log,
+		send:   send,
+		queue:  make(chan *callElement, callbackQueueSize),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// start 启动发送和落盘重试协程
+func (c *callbackSender) start() {
+	c.wg.Add(2)
+	go c.loop()
+	go c.retryLoop()
+}
+
+// stop 停止接收回调，发送队列中剩余的回调，失败则落盘
+func (c *callbackSender) stop() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.mu.Unlock()
+	close(c.stopCh)
+	c.wg.Wait()
+}
+
+// push 加入回调队列
+func (c *callbackSender) push(el *callElement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		c.spool(call{el})
+		return
+	}
+	select {
+	case c.queue <- el:
+	default:
+		c.log.Warn("回调队列已满，结果写入重试文件", FieldLogID, el.LogID)
+		c.spool(call{el})
+	}
+}
+
+func (c *callbackSender) loop() {
+	defer c.wg.Done()
+	for {
+		select {
+		case el := <-c.queue:
+			c.deliver(c.drain(call{el}), true)
+		case <-c.stopCh:
+			for {
+				list := c.drain(nil)
+				if len(list) == 0 {
+					return
+				}
+				c.deliver(list, false)
+			}
+		}
+	}
+}
+
+// drain 从队列中取出回调，凑成一批
+func (c *callbackSender) drain(list call) call {
+	for len(list) < callbackBatchSize {
+		select {
+		case el := <-c.queue:
+			list = append(list, el)
+		default:
+			return list
+		}
+	}
+	return list
+}
+
+// deliver 发送一批回调，retry为true时按退避间隔重试，最终失败则落盘
+func (c *callbackSender) deliver(list call, retry bool) {
+	backoff := callbackRetryBackoff
+	for i := 0; ; i++ {
+		err := c.send(list)
+		if err == nil {
+			return
+		}
+		c.log.Error("任务回调失败", "attempt", i+1, "err", err)
+		if !retry || i >= callbackRetryTimes || !c.wait(backoff) {
+			break
+		}
+		backoff *= 2
+	}
+	c.spool(list)
+}
+
+// wait 等待重试间隔，停止时返回false
+func (c *callbackSender) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-c.stopCh:
+		return false
+	}
+}
+
+// spool 回调写入重试文件
+func (c *callbackSender) spool(list call) {
+	data, err := json.Marshal(list)
+	if err != nil {
+		c.log.Error("回调结果序列化失败", "err", err)
+		return
+	}
+	if err = os.MkdirAll(c.dir, 0755); err != nil {
+		c.log.Error("回调重试目录创建失败", "err", err)
+		return
+	}
+	name := filepath.Join(c.dir, callbackFilePrefix+strconv.FormatInt(time.Now().UnixNano(), 10)+".log")
+	//先写临时文件再重命名，避免重试时读到不完整的内容
+	if err = os.WriteFile(name+".tmp", data, 0644); err != nil {
+		c.log.Error("回调重试文件写入失败", "err", err)
+		return
+	}
+	if err = os.Rename(name+".tmp", name); err != nil {
+		c.log.Error("回调重试文件写入失败", "err", err)
+		return
+	}
+	c.log.Info("任务回调写入重试文件", "file", name)
+}
+
+func (c *callbackSender) retryLoop() {
+	defer c.wg.Done()
+	t := time.NewTimer(0) //初始立即执行，发送上次运行遗留的回调
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.retrySpool()
+			t.Reset(callbackRetryInterval)
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// retrySpool 重新发送落盘的回调，发送成功后删除文件
+func (c *callbackSender) retrySpool() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, callbackFilePrefix) || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		path := filepath.Join(c.dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			c.log.Error("回调重试文件读取失败", "err", err)
+			continue
+		}
+		var list call
+		if err = json.Unmarshal(data, &list); err != nil || len(list) == 0 {
+			c.log.Warn("回调重试文件内容无效，已删除", "file", path)
+			_ = os.Remove(path)
+			continue
+		}
+		if err = c.send(list); err != nil {
+			c.log.Error("回调重试失败", "err", err)
+			return
+		}
+		_ = os.Remove(path)
+		c.log.Info("回调重试成功", "file", path)
+	}
+}
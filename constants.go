@@ -4,4 +4,8 @@ package xxl
 const (
 	SuccessCode = 200
 	FailureCode = 500
+	TimeoutCode = 502
 )
+
+// 请求令牌header
+const accessTokenHeader = "XXL-JOB-ACCESS-TOKEN"
@@ -89,3 +89,27 @@ type LogResContent struct {
 	LogContent  string `json:"logContent"`  // 本次请求日志内容
 	IsEnd       bool   `json:"isEnd"`       // 日志是否全部加载完
 }
+
+/*****************  状态查询  *********************/
+
+// TaskStatus 注册的任务
+type TaskStatus struct {
+	Pattern      string `json:"pattern"`                // 任务标识
+	Description  string `json:"description"`            // 任务描述
+	ScheduleType string `json:"scheduleType,omitempty"` // 调度类型，只在本地注册时为空
+	Cron         string `json:"cron"`                   // 调度配置
+}
+
+// RunningStatus 运行中的任务
+type RunningStatus struct {
+	JobID          int64  `json:"jobId"`          // 任务ID
+	LogID          int64  `json:"logId"`          // 本次调度日志ID
+	Handler        string `json:"handler"`        // 任务标识，GLUE任务为GLUE类型
+	Params         string `json:"params"`         // 任务参数
+	StartTime      string `json:"startTime"`      // 开始时间
+	ElapsedMs      int64  `json:"elapsedMs"`      // 已运行时间，单位毫秒
+	BroadcastIndex int64  `json:"broadcastIndex"` // 分片参数：当前分片
+	BroadcastTotal int64  `json:"broadcastTotal"` // 分片参数：总分片
+	Queued         int    `json:"queued"`         // 串行队列中等待的调度数
+	Waiting        bool   `json:"waiting"`        // 是否在等待并发空闲
+}
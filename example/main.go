@@ -11,26 +11,35 @@ import (
 func main() {
 	exec := xxl.NewExecutor(
 		xxl.ServerAddr("http://localhost:1184/xxl-job-admin"),
-		xxl.AccessToken("default_token"),       //请求令牌(默认为空)
-		xxl.ExecutorIp("10.3.208.81"),          //可自动获取
-		xxl.ExecutorPort("9999"),               //默认9999（非必填）
-		xxl.RegistryKey("gsy-golang-jobs-001"), //执行器名称
-		xxl.SetRegistryAlias("gsy测试执行器"),       // 设置别名
-		xxl.SetLogger(&logger{}),               //自定义日志
-		xxl.SetAdminPwd("123456"),              // 超管密码
+		xxl.AccessToken("default_token"),         //请求令牌(默认为空)
+		xxl.ExecutorIp("10.3.208.81"),            //可自动获取
+		xxl.ExecutorPort("9999"),                 //默认9999（非必填）
+		xxl.RegistryKey("gsy-golang-jobs-001"),   //执行器名称
+		xxl.SetRegistryAlias("gsy测试执行器"),         // 设置别名
+		xxl.SetLogger(&logger{}),                 //自定义日志
+		xxl.SetAdminUser("admin"),                // 超管用户名
+		xxl.SetAdminPwd("123456"),                // 超管密码
+		xxl.SetLogDir("/tmp/xxl-job/jobhandler"), //执行日志目录
 	)
-	exec.Init()
-	//设置日志查看handler
-	exec.LogHandler(func(req *xxl.LogReq) *xxl.LogRes {
-		return &xxl.LogRes{Code: xxl.SuccessCode, Msg: "", Content: xxl.LogResContent{
-			FromLineNum: req.FromLineNum,
-			ToLineNum:   2,
-			LogContent:  "这个是自定义日志handler",
-			IsEnd:       true,
-		}}
-	})
+	if err := exec.Init(); err != nil {
+		log.Println("同步执行器失败：", err)
+	}
 	//注册任务handler
 	exec.RegTask("task.test-001", "描述1", "0/1 * * * * ?", task.Test)
+	exec.RegJob("task.error", "返回错误", "0 0/5 * * * ?", task.Error)
+	exec.RegJob("task.sync", "类型化参数", "0 0/10 * * * ?", task.Sync)
+	//只在本地注册，由调度中心手动触发或由父任务触发
+	exec.RegHandler("task.manual", task.Sync, xxl.WithJobSpec(xxl.JobSpec{Desc: "手动触发", ScheduleType: xxl.ScheduleNone}))
+	//声明调度中心任务，只同步声明了的字段，调度中心上修改的其他字段不会被覆盖
+	retry := 3
+	exec.RegHandler("task.shard", task.Sync, xxl.WithJobSpec(xxl.JobSpec{
+		Desc:          "分片广播",
+		Author:        "ops",
+		ScheduleType:  xxl.ScheduleFixRate,
+		ScheduleConf:  "60",
+		RouteStrategy: xxl.RouteShardingBroadcast,
+		RetryCount:    &retry,
+	}))
 	log.Fatal(exec.Run())
 }
 
@@ -0,0 +1,15 @@
+package task
+
+import (
+	"context"
+	"errors"
+
+	xxl "github.com/open-beagle/xxl-job-executor-go"
+)
+
+func Error(cxt context.Context, param *xxl.RunReq) (msg string, err error) {
+	if param.ExecutorParams == "" {
+		return "", errors.New("param is required")
+	}
+	return "error done", nil
+}
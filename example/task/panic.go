@@ -7,5 +7,4 @@ import (
 
 func Panic(cxt context.Context, param *xxl.RunReq) (msg string) {
 	panic("test panic")
-	return
 }
@@ -8,5 +8,6 @@ import (
 
 func Test(cxt context.Context, param *xxl.RunReq) (msg string) {
 	fmt.Println("test one task" + param.ExecutorHandler + " param：" + param.ExecutorParams + " log_id:" + xxl.Int64ToStr(param.LogID))
+	xxl.GetJobLogger(cxt).Info("test one task param: %s", param.ExecutorParams)
 	return "test done"
 }
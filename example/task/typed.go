@@ -0,0 +1,21 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	xxl "github.com/open-beagle/xxl-job-executor-go"
+)
+
+// SyncArgs 任务参数，可配置为 {"table":"user","batch":100}、table=user&batch=100 或 --table user --batch 100
+type SyncArgs struct {
+	Table  string `json:"table"`
+	Batch  int    `json:"batch"`
+	DryRun bool   `json:"dryRun"`
+}
+
+// Sync 参数为空时使用注册时的默认值
+var Sync = xxl.TypedJob(SyncArgs{Table: "user", Batch: 100}, func(cxt context.Context, param *xxl.RunReq, args SyncArgs) (string, error) {
+	xxl.GetJobLogger(cxt).Info("sync table %s, batch %d, dryRun %v", args.Table, args.Batch, args.DryRun)
+	return fmt.Sprintf("sync %s done", args.Table), nil
+})
@@ -2,12 +2,16 @@ package xxl
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
-	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
@@ -16,12 +20,18 @@ import (
 
 // Executor 执行器
 type Executor interface {
-	// Init 初始化
-	Init(...Option)
+	// Init 初始化，设置超管密码时同步执行器到调度中心，同步失败时返回错误
+	Init(...Option) error
 	// LogHandler 日志查询
 	LogHandler(handler LogHandler)
+	// RegHandler 只在本地注册任务，通过WithJobSpec声明调度中心任务
+	RegHandler(pattern string, job JobFunc, opts ...TaskOption) error
 	// RegTask 注册任务
-	RegTask(pattern, jobDes, scheduleConf string, task TaskFunc)
+	RegTask(pattern, jobDes, scheduleConf string, task TaskFunc, opts ...TaskOption) error
+	// RegJob 注册可返回错误的任务
+	RegJob(pattern, jobDes, scheduleConf string, job JobFunc, opts ...TaskOption) error
+	// Use 添加全局任务中间件
+	Use(mws ...Middleware)
 	// RunTask 运行任务
 	RunTask(writer http.ResponseWriter, request *http.Request)
 	// KillTask 杀死任务
@@ -32,8 +42,20 @@ type Executor interface {
 	Beat(writer http.ResponseWriter, request *http.Request)
 	// IdleBeat 忙碌检测
 	IdleBeat(writer http.ResponseWriter, request *http.Request)
-	// Run 运行服务
+	// Metrics 监控指标，Prometheus文本格式
+	Metrics(writer http.ResponseWriter, request *http.Request)
+	// StatusTasks 注册的任务列表
+	StatusTasks(writer http.ResponseWriter, request *http.Request)
+	// StatusRunning 运行中的任务列表
+	StatusRunning(writer http.ResponseWriter, request *http.Request)
+	// Handler 执行器的所有路由，可挂载到已有的http服务
+	Handler() http.Handler
+	// Register 注册到调度中心并保持心跳，不监听端口，cxt取消后停止执行器
+	Register(cxt context.Context)
+	// Run 运行服务，收到退出信号后停止
 	Run() error
+	// Start 运行服务，cxt取消后停止，不处理系统信号
+	Start(cxt context.Context) error
 	// Stop 停止服务
 	Stop()
 }
@@ -54,30 +76,75 @@ func newExecutor(opts ...Option) *executor {
 type executor struct {
 	opts    Options
 	address string
-	regList *taskList //注册任务列表
-	runList *taskList //正在执行任务列表
+	regList *handlerList //注册任务列表
+	runList *taskList    //正在执行任务列表
+	queue   *taskQueue   //单机串行等待队列
+	pool    *pool        //并发控制
 	mu      sync.RWMutex
-	log     Logger
+	log     StructuredLogger
+
+	middlewares []Middleware //全局任务中间件，第一个为Recovery
 
 	logHandler LogHandler //日志查询handler
-	xxl        xxlApi
+	xxl        *xxlApi
+	admin      *adminClient    //调度中心地址
+	callbacks  *callbackSender //任务结果回调
+	metrics    *metrics        //监控统计
+
+	server       *http.Server
+	closing      bool          //停止中，不再接收新的调度
+	stopCh       chan struct{} //停止注册心跳
+	stopOnce     sync.Once
+	registryOnce sync.Once
 }
 
-func (e *executor) Init(opts ...Option) {
+func (e *executor) Init(opts ...Option) error {
 	for _, o := range opts {
 		o(&e.opts)
 	}
-	e.log = e.opts.l
-	e.regList = &taskList{
-		data: make(map[string]*Task),
+	e.log = e.opts.sl
+	if e.log == nil {
+		e.log = FromLogger(e.opts.l)
+	}
+	if e.opts.EnableGlue && e.opts.AccessToken == "" {
+		e.log.Error("开启GLUE脚本任务必须设置AccessToken")
+		return errGlueWithoutToken
+	}
+	e.middlewares = []Middleware{Recovery(e.log)}
+	e.regList = &handlerList{
+		data: make(map[string]*jobHandler),
 	}
 	e.runList = &taskList{
 		data: make(map[string]*Task),
 	}
+	e.queue = &taskQueue{
+		data: make(map[string][]*Task),
+	}
+	e.pool = newPool(e.opts.MaxConcurrency, e.opts.PoolQueueSize)
 	e.address = e.opts.ExecutorIp + ":" + e.opts.ExecutorPort
-	go e.registry()
-	e.xxl = *newXxlApi(e.opts)
-	e.xxl.checkOrAddExecutor(e.opts.RegistryKey, e.opts.RegistryAlias, e.opts.AddressList)
+	if e.logHandler == nil {
+		e.logHandler = FileLogHandler(e.opts.LogDir)
+	}
+	if e.opts.logSink == nil {
+		e.opts.logSink = FileJobLogSink(e.opts.LogDir)
+	}
+	e.stopCh = make(chan struct{})
+	e.admin = newAdminClient(e.opts.ServerAddr, e.opts.AdminRoute)
+	e.metrics = newMetrics()
+	e.callbacks = newCallbackSender(filepath.Join(e.opts.LogDir, "callbacklog"), e.log, e.postCallback)
+	e.callbacks.start()
+	if e.opts.RegistryOnInit {
+		e.startRegistry()
+	}
+	go e.logCleaner()
+	e.xxl = newXxlApi(e.opts, e.log, e.admin)
+	if e.opts.AdminPwd != "" {
+		if err := e.xxl.checkOrAddExecutor(e.opts.RegistryKey, e.opts.RegistryAlias, e.opts.AddressList); err != nil {
+			e.log.Error("同步执行器失败", "err", err)
+			return err
+		}
+	}
+	return nil
 }
 
 // LogHandler 日志handler
@@ -85,7 +152,16 @@ func (e *executor) LogHandler(handler LogHandler) {
 	e.logHandler = handler
 }
 
+// Run 运行服务，收到SIGINT、SIGTERM、SIGQUIT后停止
 func (e *executor) Run() (err error) {
+	cxt, stop := signal.NotifyContext(context.Background(), syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return e.Start(cxt)
+}
+
+// Handler 执行器的所有路由，设置了AddressPath时同时响应带路径前缀的请求，
+// 可挂载到已有的http服务，服务开始监听后调用Register注册到调度中心
+func (e *executor) Handler() http.Handler {
 	// 创建路由器
 	mux := http.NewServeMux()
 	// 设置路由规则
@@ -94,128 +170,364 @@ func (e *executor) Run() (err error) {
 	mux.HandleFunc("/log", e.taskLog)
 	mux.HandleFunc("/beat", e.beat)
 	mux.HandleFunc("/idleBeat", e.idleBeat)
+	if e.opts.EnableMetrics {
+		mux.HandleFunc("/metrics", e.metricsHandler)
+	}
+	if e.opts.EnableStatus {
+		mux.HandleFunc("/status/tasks", e.statusTasks)
+		mux.HandleFunc("/status/running", e.statusRunning)
+	}
+	prefix := strings.TrimSuffix(addressPath(e.opts.AddressPath), "/")
+	if prefix == "" {
+		return mux
+	}
+	stripped := http.StripPrefix(prefix, mux)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if strings.HasPrefix(request.URL.Path, prefix+"/") {
+			stripped.ServeHTTP(writer, request)
+			return
+		}
+		mux.ServeHTTP(writer, request)
+	})
+}
+
+// Register 注册到调度中心并保持心跳直到cxt取消，然后摘除注册并停止执行器，不监听端口；
+// 开启RegistryOnInit时心跳已由Init启动，不会重复注册
+func (e *executor) Register(cxt context.Context) {
+	e.startRegistry()
+	select {
+	case <-cxt.Done():
+	case <-e.stopCh:
+	}
+	e.Stop()
+}
+
+// startRegistry 启动注册心跳，只启动一次
+func (e *executor) startRegistry() {
+	e.registryOnce.Do(func() {
+		go e.registry()
+	})
+}
+
+// Start 运行服务，cxt取消后停止并返回，不处理系统信号；监听失败时返回错误
+func (e *executor) Start(cxt context.Context) error {
 	// 创建服务器
 	server := &http.Server{
 		Addr:         ":" + e.opts.ExecutorPort,
 		WriteTimeout: time.Second * 3,
-		Handler:      mux,
-	}
-	// 监听端口并提供服务
-	e.log.Info("Starting server at " + e.address)
-	go server.ListenAndServe()
-	quit := make(chan os.Signal)
-	signal.Notify(quit, syscall.SIGKILL, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	e.registryRemove()
-	return nil
+		Handler:      e.Handler(),
+	}
+	tlsConfig, err := e.serverTLSConfig()
+	if err != nil {
+		e.log.Error("HTTPS配置错误", "err", err)
+		e.Stop()
+		return err
+	}
+	// 监听端口
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		e.log.Error("监听端口失败", "err", err)
+		e.Stop()
+		return err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	e.mu.Lock()
+	e.server = server
+	e.mu.Unlock()
+	// 提供服务
+	e.log.Info("Starting server", "address", e.address)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(ln)
+	}()
+	e.startRegistry()
+	select {
+	case <-cxt.Done():
+		e.Stop()
+		return nil
+	case err = <-errCh:
+		e.Stop()
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		e.log.Error("服务异常退出", "err", err)
+		return err
+	}
 }
 
+// Stop 停止接收调度，摘除注册，等待运行中的任务结束后关闭服务
 func (e *executor) Stop() {
-	e.registryRemove()
+	e.stopOnce.Do(e.shutdown)
 }
 
-// RegTask 注册任务
-func (e *executor) RegTask(pattern, jobDes, scheduleConf string, task TaskFunc) {
-	var t = &Task{}
-	t.fn = task
-	e.regList.Set(pattern, t)
-	e.xxl.checkOrAddJob(jobDes, scheduleConf, pattern)
+func (e *executor) shutdown() {
+	e.mu.Lock()
+	e.closing = true
+	e.mu.Unlock()
+	close(e.stopCh)
+	e.registryRemove()
+
+	//等待运行中和串行队列中的任务结束
+	deadline := time.Now().Add(e.opts.ShutdownTimeout)
+	for e.runList.Len() > 0 || e.queue.Size() > 0 {
+		if time.Now().After(deadline) {
+			e.log.Warn("等待任务结束超时，取消剩余任务", "remaining", e.runList.Len()+e.queue.Size())
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	//取消未结束的任务并回调失败
+	e.mu.Lock()
+	queued := e.queue.ClearAll()
+	running := e.runList.GetAll()
+	e.mu.Unlock()
+	for _, task := range queued {
+		e.sendCallback(task, FailureCode, "executor shutdown")
+	}
+	for _, task := range running {
+		task := task
+		task.finish(FailureCode, "executor shutdown", outcomeKilled, func(code int64, msg string) {
+			e.callback(task, code, msg)
+		})
+	}
+
+	e.callbacks.stop()
+
+	e.mu.RLock()
+	server := e.server
+	e.mu.RUnlock()
+	if server != nil {
+		cxt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(cxt); err != nil {
+			e.log.Error("服务关闭失败", "err", err)
+		}
+	}
+	e.log.Info("执行器已停止")
 }
 
-// 运行一个任务
-func (e *executor) runTask(writer http.ResponseWriter, request *http.Request) {
+// Use 添加全局任务中间件，按添加顺序执行，在Recovery之后
+func (e *executor) Use(mws ...Middleware) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	e.middlewares = append(e.middlewares, mws...)
+}
 
+// RegHandler 只在本地注册任务，声明了调度中心任务时同步到调度中心，同步失败时任务仍在本地注册并返回错误
+func (e *executor) RegHandler(pattern string, job JobFunc, opts ...TaskOption) error {
+	h := &jobHandler{
+		name: pattern,
+		fn:   job,
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	e.regList.Set(pattern, h)
+	return e.provision(h)
+}
+
+// RegTask 注册任务，scheduleConf为CRON表达式，为空时不自动调度
+func (e *executor) RegTask(pattern, jobDes, scheduleConf string, task TaskFunc, opts ...TaskOption) error {
+	return e.RegJob(pattern, jobDes, scheduleConf, task.Job(), opts...)
+}
+
+// RegJob 注册可返回错误的任务，scheduleConf为CRON表达式，为空时不自动调度
+func (e *executor) RegJob(pattern, jobDes, scheduleConf string, job JobFunc, opts ...TaskOption) error {
+	opts = append([]TaskOption{WithJobSpec(cronSpec(jobDes, scheduleConf))}, opts...)
+	return e.RegHandler(pattern, job, opts...)
+}
+
+// 运行一个任务
+func (e *executor) runTask(writer http.ResponseWriter, request *http.Request) {
+	if !e.checkToken(writer, request) {
+		return
+	}
 	req, _ := ioutil.ReadAll(request.Body)
 	param := &RunReq{}
 	err := json.Unmarshal(req, &param)
 	if err != nil {
 		_, _ = writer.Write(returnCall(param, FailureCode, "params err"))
-		e.log.Error("参数解析错误:" + string(req))
+		e.log.Error("参数解析错误", "body", string(req))
 		return
 	}
-	e.log.Info("任务参数:%v", param)
-	if !e.regList.Exists(param.ExecutorHandler) {
-		_, _ = writer.Write(returnCall(param, FailureCode, "Task not registered"))
-		e.log.Error("任务[" + Int64ToStr(param.JobID) + "]没有注册:" + param.ExecutorHandler)
-		return
+	e.log.Debug("任务参数", "param", param)
+	var handler *jobHandler
+	if param.GlueType == "" || param.GlueType == glueBean {
+		e.metrics.trigger(param.ExecutorHandler)
+		handler = e.regList.Get(param.ExecutorHandler)
+		if handler == nil {
+			_, _ = writer.Write(returnCall(param, FailureCode, "Task not registered"))
+			e.log.Error("任务没有注册", jobFields(param)...)
+			return
+		}
+	} else {
+		e.metrics.trigger(param.GlueType)
+		if !e.opts.EnableGlue {
+			_, _ = writer.Write(returnFail("glueType[" + param.GlueType + "] is not enabled."))
+			e.log.Warn("未开启GLUE脚本任务", jobFields(param)...)
+			return
+		}
+		handler = e.glueHandler(param)
+		if handler == nil {
+			_, _ = writer.Write(returnFail("glueType[" + param.GlueType + "] is not valid."))
+			e.log.Error("不支持的GLUE类型", jobFields(param)...)
+			return
+		}
 	}
 
+	_, _ = writer.Write(e.dispatch(handler, param))
+}
+
+// dispatch 按阻塞策略处理调度，返回调度中心的响应；只在处理调度时持有e.mu，不阻塞其他路由
+func (e *executor) dispatch(handler *jobHandler, param *RunReq) []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closing {
+		e.log.Warn("执行器停止中，拒绝任务", jobFields(param)...)
+		return returnFail("executor is shutting down")
+	}
 	//阻塞策略处理
+	task := handler.newTask(param, e.middlewares)
 	if e.runList.Exists(Int64ToStr(param.JobID)) {
 		if param.ExecutorBlockStrategy == coverEarly { //覆盖之前调度
 			oldTask := e.runList.Get(Int64ToStr(param.JobID))
 			if oldTask != nil {
-				oldTask.Cancel()
-				e.runList.Del(Int64ToStr(oldTask.Id))
+				oldTask.kill("block strategy effect：Cover Early [job running, killed]")
+				e.runList.CompareAndDel(Int64ToStr(oldTask.Id), oldTask)
 			}
-		} else { //单机串行,丢弃后续调度 都进行阻塞
-			_, _ = writer.Write(returnCall(param, FailureCode, "There are tasks running"))
-			e.log.Error("任务[" + Int64ToStr(param.JobID) + "]已经在运行了:" + param.ExecutorHandler)
-			return
+			e.discardQueue(param.JobID, "block strategy effect：Cover Early [job not executed, in the job queue, killed]")
+		} else if param.ExecutorBlockStrategy == serialExecution { //单机串行
+			if !e.queue.Push(Int64ToStr(param.JobID), task, e.opts.SerialQueueSize) {
+				e.metrics.reject(serialExecution)
+				e.log.Warn("串行队列已满", jobFields(param)...)
+				return returnFail("The serial queue is full")
+			}
+			e.log.Info("加入串行队列", jobFields(param)...)
+			return returnGeneral()
+		} else { //丢弃后续调度
+			e.metrics.reject(discardLater)
+			e.log.Warn("任务已经在运行了", jobFields(param)...)
+			return returnCall(param, FailureCode, "There are tasks running")
 		}
 	}
 
+	if err := e.startTask(task, e.opts.OverflowPolicy); err != nil {
+		e.metrics.reject("MAX_CONCURRENCY")
+		e.log.Warn("并发已满，拒绝任务", append(jobFields(param), "err", err)...)
+		return returnFail(err.Error())
+	}
+	e.log.Info("任务开始执行", jobFields(param)...)
+	return returnGeneral()
+}
+
+// 启动任务实例，并发已满时按policy拒绝或等待，超时从此刻开始计算
+func (e *executor) startTask(task *Task, policy string) error {
+	ticket, err := e.pool.reserve(task.Name, task.limit, policy)
+	if err != nil {
+		return err
+	}
+	task.ticket = ticket
 	cxt := context.Background()
-	task := e.regList.Get(param.ExecutorHandler)
-	if param.ExecutorTimeout > 0 {
-		task.Ext, task.Cancel = context.WithTimeout(cxt, time.Duration(param.ExecutorTimeout)*time.Second)
+	if task.Param.ExecutorTimeout > 0 {
+		task.Ext, task.Cancel = context.WithTimeout(cxt, time.Duration(task.Param.ExecutorTimeout)*time.Second)
 	} else {
 		task.Ext, task.Cancel = context.WithCancel(cxt)
 	}
-	task.Id = param.JobID
-	task.Name = param.ExecutorHandler
-	task.Param = param
+	task.StartTime = time.Now().UnixMilli()
 	task.log = e.log
+	task.logFile = newJobLogFile(e.opts.LogDir, task.Param)
+	task.Ext = withJobLogger(task.Ext, newJobLogger(e.opts.logSink, task.Param))
+	task.Ext = withRunReq(task.Ext, task.Param)
+	task.logFile.start()
 
 	e.runList.Set(Int64ToStr(task.Id), task)
-	go task.Run(func(code int64, msg string) {
+	go e.execute(task)
+	return nil
+}
+
+// 等待并发空闲后执行任务，等待中被终止或超时的任务直接回调
+func (e *executor) execute(task *Task) {
+	callback := func(code int64, msg string) {
 		e.callback(task, code, msg)
-	})
-	e.log.Info("任务[" + Int64ToStr(param.JobID) + "]开始执行:" + param.ExecutorHandler)
-	_, _ = writer.Write(returnGeneral())
+	}
+	if !e.pool.wait(task.Ext, task.ticket) {
+		code, msg, outcome := task.result("", nil)
+		task.finish(code, msg, outcome, callback)
+		return
+	}
+	defer e.pool.release(task.ticket)
+	task.Run(callback)
+}
+
+// 丢弃串行队列中等待的调度，每个调度单独回调失败，返回丢弃数量
+func (e *executor) discardQueue(jobID int64, msg string) int {
+	list := e.queue.Clear(Int64ToStr(jobID))
+	for _, task := range list {
+		go e.sendCallback(task, FailureCode, msg)
+	}
+	if len(list) > 0 {
+		e.log.Info("丢弃串行队列中的调度", FieldJobID, jobID, "count", len(list))
+	}
+	return len(list)
 }
 
 // 删除一个任务
 func (e *executor) killTask(writer http.ResponseWriter, request *http.Request) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	if !e.checkToken(writer, request) {
+		return
+	}
 	req, _ := ioutil.ReadAll(request.Body)
 	param := &killReq{}
 	_ = json.Unmarshal(req, &param)
-	if !e.runList.Exists(Int64ToStr(param.JobID)) {
+	if !e.kill(param.JobID) {
 		_, _ = writer.Write(returnKill(param, FailureCode))
-		e.log.Error("任务[" + Int64ToStr(param.JobID) + "]没有运行")
+		e.log.Warn("任务没有运行", FieldJobID, param.JobID)
 		return
 	}
-	task := e.runList.Get(Int64ToStr(param.JobID))
-	task.Cancel()
-	e.runList.Del(Int64ToStr(param.JobID))
 	_, _ = writer.Write(returnGeneral())
 }
 
+// kill 终止任务并丢弃串行队列中的调度，任务没有运行也没有等待的调度时返回false
+func (e *executor) kill(jobID int64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	discarded := e.discardQueue(jobID, "job not executed, in the job queue, killed.")
+	task := e.runList.Get(Int64ToStr(jobID))
+	if task == nil {
+		return discarded > 0
+	}
+	task.kill("scheduling center kill job.")
+	e.runList.CompareAndDel(Int64ToStr(jobID), task)
+	return true
+}
+
 // 任务日志
 func (e *executor) taskLog(writer http.ResponseWriter, request *http.Request) {
+	if !e.checkToken(writer, request) {
+		return
+	}
 	var res *LogRes
 	data, err := ioutil.ReadAll(request.Body)
 	req := &LogReq{}
 	if err != nil {
-		e.log.Error("日志请求失败:" + err.Error())
+		e.log.Error("日志请求失败", "err", err)
 		reqErrLogHandler(writer, req, err)
 		return
 	}
 	err = json.Unmarshal(data, &req)
 	if err != nil {
-		e.log.Error("日志请求解析失败:" + err.Error())
+		e.log.Error("日志请求解析失败", "err", err)
 		reqErrLogHandler(writer, req, err)
 		return
 	}
-	e.log.Info("日志请求参数:%+v", req)
+	e.log.Debug("日志请求参数", FieldLogID, req.LogID, "fromLineNum", req.FromLineNum)
 	if e.logHandler != nil {
 		res = e.logHandler(req)
 	} else {
-		res = defaultLogHandler(req)
+		res = FileLogHandler(e.opts.LogDir)(req)
 	}
 	str, _ := json.Marshal(res)
 	_, _ = writer.Write(str)
@@ -223,32 +535,65 @@ func (e *executor) taskLog(writer http.ResponseWriter, request *http.Request) {
 
 // 心跳检测
 func (e *executor) beat(writer http.ResponseWriter, request *http.Request) {
-	e.log.Info("心跳检测")
+	if !e.checkToken(writer, request) {
+		return
+	}
+	e.log.Debug("心跳检测")
 	_, _ = writer.Write(returnGeneral())
 }
 
 // 忙碌检测
 func (e *executor) idleBeat(writer http.ResponseWriter, request *http.Request) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	if !e.checkToken(writer, request) {
+		return
+	}
 	defer request.Body.Close()
 	req, _ := ioutil.ReadAll(request.Body)
 	param := &idleBeatReq{}
 	err := json.Unmarshal(req, &param)
 	if err != nil {
 		_, _ = writer.Write(returnIdleBeat(FailureCode))
-		e.log.Error("参数解析错误:" + string(req))
+		e.log.Error("参数解析错误", "body", string(req))
 		return
 	}
-	if e.runList.Exists(Int64ToStr(param.JobID)) {
-		_, _ = writer.Write(returnIdleBeat(FailureCode))
-		e.log.Error("idleBeat任务[" + Int64ToStr(param.JobID) + "]正在运行")
+	//只读取并发安全的运行列表、串行队列和并发控制，自定义检测可能较慢，不持有e.mu
+	if err = e.idle(request.Context(), param.JobID); err != nil {
+		_, _ = writer.Write(returnBusy(err.Error()))
+		e.log.Info("idleBeat忙碌", FieldJobID, param.JobID, "reason", err)
 		return
 	}
-	e.log.Info("忙碌检测任务参数:%v", param)
+	e.log.Debug("忙碌检测", FieldJobID, param.JobID)
 	_, _ = writer.Write(returnGeneral())
 }
 
+// 校验调度中心请求令牌，未设置AccessToken时不校验
+func (e *executor) checkToken(writer http.ResponseWriter, request *http.Request) bool {
+	if e.opts.AccessToken == "" {
+		return true
+	}
+	token := request.Header.Get(accessTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(e.opts.AccessToken)) == 1 {
+		return true
+	}
+	_, _ = writer.Write(returnTokenErr())
+	e.log.Warn("请求令牌错误", "path", request.URL.Path, "remote", request.RemoteAddr)
+	return false
+}
+
+// addressPath 规范化注册地址的路径，非空时以/开头和结尾
+func addressPath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path + "/"
+}
+
+// registryValue 注册地址，调度中心在其后拼接run、kill等路由
+func (e *executor) registryValue() string {
+	return e.scheme() + "://" + e.address + addressPath(e.opts.AddressPath)
+}
+
 // 注册执行器到调度中心
 func (e *executor) registry() {
 
@@ -257,34 +602,42 @@ func (e *executor) registry() {
 	req := &Registry{
 		RegistryGroup: "EXECUTOR",
 		RegistryKey:   e.opts.RegistryKey,
-		RegistryValue: "http://" + e.address,
+		RegistryValue: e.registryValue(),
 	}
 	param, err := json.Marshal(req)
 	if err != nil {
 		log.Fatal("执行器注册信息解析失败:" + err.Error())
 	}
 	for {
-		<-t.C
+		select {
+		case <-t.C:
+		case <-e.stopCh:
+			return
+		}
 		t.Reset(time.Second * time.Duration(20)) //20秒心跳防止过期
 		func() {
 			result, err := e.post("/api/registry", string(param))
 			if err != nil {
-				e.log.Error("执行器注册失败1:" + err.Error())
+				e.metrics.registry(false)
+				e.log.Error("执行器注册失败", "err", err)
 				return
 			}
 			defer result.Body.Close()
 			body, err := ioutil.ReadAll(result.Body)
 			if err != nil {
-				e.log.Error("执行器注册失败2:" + err.Error())
+				e.metrics.registry(false)
+				e.log.Error("执行器注册失败", "err", err)
 				return
 			}
 			res := &res{}
 			_ = json.Unmarshal(body, &res)
 			if res.Code != SuccessCode {
-				e.log.Error("执行器注册失败3:" + string(body))
+				e.metrics.registry(false)
+				e.log.Error("执行器注册失败", "body", string(body))
 				return
 			}
-			e.log.Info("执行器注册成功:" + string(body))
+			e.metrics.registry(true)
+			e.log.Debug("执行器注册成功", "body", string(body))
 		}()
 
 	}
@@ -297,52 +650,93 @@ func (e *executor) registryRemove() {
 	req := &Registry{
 		RegistryGroup: "EXECUTOR",
 		RegistryKey:   e.opts.RegistryKey,
-		RegistryValue: "http://" + e.address,
+		RegistryValue: e.registryValue(),
 	}
 	param, err := json.Marshal(req)
 	if err != nil {
-		e.log.Error("执行器摘除失败:" + err.Error())
+		e.log.Error("执行器摘除失败", "err", err)
 		return
 	}
 	res, err := e.post("/api/registryRemove", string(param))
 	if err != nil {
-		e.log.Error("执行器摘除失败:" + err.Error())
+		e.log.Error("执行器摘除失败", "err", err)
 		return
 	}
 	defer res.Body.Close()
 	body, err := ioutil.ReadAll(res.Body)
-	e.log.Info("执行器摘除成功:" + string(body))
+	e.log.Info("执行器摘除成功", "body", string(body))
 }
 
-// 回调任务列表
+// 任务结束，从运行列表移除并启动串行队列中的下一个调度
 func (e *executor) callback(task *Task, code int64, msg string) {
-	e.runList.Del(Int64ToStr(task.Id))
-	res, err := e.post("/api/callback", string(returnCall(task.Param, code, msg)))
+	e.mu.Lock()
+	e.runList.CompareAndDel(Int64ToStr(task.Id), task)
+	if !e.runList.Exists(Int64ToStr(task.Id)) {
+		if next := e.queue.Pop(Int64ToStr(task.Id)); next != nil {
+			if err := e.startTask(next, overflowWait); err != nil {
+				go e.sendCallback(next, FailureCode, err.Error())
+			} else {
+				e.log.Info("串行队列任务开始执行", jobFields(next.Param)...)
+			}
+		}
+	}
+	e.mu.Unlock()
+	e.metrics.run(task.Name, task.outcome, float64(task.EndTime-task.StartTime)/1000)
+	e.sendCallback(task, code, msg)
+}
+
+// 回调任务结果
+func (e *executor) sendCallback(task *Task, code int64, msg string) {
+	if task.logFile == nil {
+		task.logFile = newJobLogFile(e.opts.LogDir, task.Param)
+	}
+	task.logFile.end(code, msg)
+	e.callbacks.push(newCallElement(task.Param, code, msg))
+}
+
+// 发送一批任务回调，调度中心未返回成功时返回错误
+func (e *executor) postCallback(list call) (err error) {
+	defer func() {
+		if err != nil {
+			e.metrics.callbackFailed()
+		}
+	}()
+	data, err := json.Marshal(list)
 	if err != nil {
-		e.log.Error("callback err : ", err.Error())
-		return
+		return err
 	}
-	defer res.Body.Close()
-	body, err := ioutil.ReadAll(res.Body)
+	result, err := e.post("/api/callback", string(data))
 	if err != nil {
-		e.log.Error("callback ReadAll err : ", err.Error())
-		return
+		return err
+	}
+	defer result.Body.Close()
+	body, err := ioutil.ReadAll(result.Body)
+	if err != nil {
+		return err
 	}
-	e.log.Info("任务回调成功:" + string(body))
+	res := &res{}
+	_ = json.Unmarshal(body, &res)
+	if result.StatusCode != http.StatusOK || res.Code != SuccessCode {
+		return fmt.Errorf("status %d: %s", result.StatusCode, string(body))
+	}
+	e.log.Debug("任务回调成功", "count", len(list), "body", string(body))
+	return nil
 }
 
 // post
 func (e *executor) post(action, body string) (resp *http.Response, err error) {
-	request, err := http.NewRequest("POST", e.opts.ServerAddr+action, strings.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-	request.Header.Set("Content-Type", "application/json;charset=UTF-8")
-	request.Header.Set("XXL-JOB-ACCESS-TOKEN", e.opts.AccessToken)
-	client := http.Client{
-		Timeout: e.opts.Timeout,
+	client := &http.Client{
+		Timeout: adminTimeout(e.opts.Timeout),
 	}
-	return client.Do(request)
+	return e.admin.do(func(addr string) (*http.Request, error) {
+		request, err := http.NewRequest("POST", addr+action, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/json;charset=UTF-8")
+		request.Header.Set(accessTokenHeader, e.opts.AccessToken)
+		return request, nil
+	}, client)
 }
 
 // RunTask 运行任务
@@ -369,3 +763,18 @@ func (e *executor) Beat(writer http.ResponseWriter, request *http.Request) {
 func (e *executor) IdleBeat(writer http.ResponseWriter, request *http.Request) {
 	e.idleBeat(writer, request)
 }
+
+// Metrics 监控指标
+func (e *executor) Metrics(writer http.ResponseWriter, request *http.Request) {
+	e.metricsHandler(writer, request)
+}
+
+// StatusTasks 注册的任务列表
+func (e *executor) StatusTasks(writer http.ResponseWriter, request *http.Request) {
+	e.statusTasks(writer, request)
+}
+
+// StatusRunning 运行中的任务列表
+func (e *executor) StatusRunning(writer http.ResponseWriter, request *http.Request) {
+	e.statusRunning(writer, request)
+}
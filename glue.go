@@ -0,0 +1,151 @@
+package xxl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+/**
+GLUE脚本任务，需SetGlueEnabled开启并设置AccessToken，GlueSource写入LogDir/gluesource/<jobId>_<glueUpdatetime>.<后缀>，由对应解释器执行
+*/
+
+const glueBean = "BEAN"
+
+// errGlueWithoutToken 没有请求令牌时任何能访问执行器的人都可以下发脚本，不允许开启GLUE
+var errGlueWithoutToken = errors.New("GLUE脚本任务需要设置AccessToken")
+
+// glueScript 脚本类型对应的解释器和文件后缀
+type glueScript struct {
+	cmd    string
+	suffix string
+}
+
+var glueScripts = map[string]glueScript{
+	"GLUE_SHELL":      {cmd: "bash", suffix: ".sh"},
+	"GLUE_PYTHON":     {cmd: "python", suffix: ".py"},
+	"GLUE_PHP":        {cmd: "php", suffix: ".php"},
+	"GLUE_NODEJS":     {cmd: "node", suffix: ".js"},
+	"GLUE_POWERSHELL": {cmd: "powershell", suffix: ".ps1"},
+}
+
+// glueHandler GLUE脚本任务定义，不支持的类型返回nil
+func (e *executor) glueHandler(param *RunReq) *jobHandler {
+	script, ok := glueScripts[param.GlueType]
+	if !ok {
+		return nil
+	}
+	dir := filepath.Join(e.opts.LogDir, "gluesource")
+	return &jobHandler{
+		name: param.GlueType,
+		fn: func(cxt context.Context, param *RunReq) (string, error) {
+			return "", runGlueScript(cxt, dir, script, param)
+		},
+	}
+}
+
+// runGlueScript 执行脚本，参数依次为ExecutorParams、分片序号、分片总数，退出码不为0时返回错误
+func runGlueScript(cxt context.Context, dir string, script glueScript, param *RunReq) error {
+	jobLog := GetJobLogger(cxt)
+	file, err := glueScriptFile(dir, script, param)
+	if err != nil {
+		jobLog.Error("脚本文件写入失败:%s", err.Error())
+		return fmt.Errorf("glue script write fail: %w", err)
+	}
+	jobLog.Info("----------- script file: %s -----------", file)
+
+	out := &jobLogWriter{l: jobLog}
+	cmd := exec.Command(script.cmd, file, param.ExecutorParams, Int64ToStr(param.BroadcastIndex), Int64ToStr(param.BroadcastTotal))
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+	if err = cmd.Start(); err != nil {
+		jobLog.Error("脚本启动失败:%s", err.Error())
+		return fmt.Errorf("glue script start fail: %w", err)
+	}
+	//任务终止或超时时结束整个进程组
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-cxt.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	err = cmd.Wait()
+	close(done)
+	out.Flush()
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("script exit value(%d) is failed", exitErr.ExitCode())
+		}
+		return fmt.Errorf("script run fail: %w", err)
+	}
+	return nil
+}
+
+// glueScriptFile 写入脚本文件，GlueUpdatetime不变时复用已有文件，并删除该任务的旧版本脚本
+func glueScriptFile(dir string, script glueScript, param *RunReq) (string, error) {
+	prefix := Int64ToStr(param.JobID) + "_"
+	file := filepath.Join(dir, prefix+Int64ToStr(param.GlueUpdatetime)+script.suffix)
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if old, err := filepath.Glob(filepath.Join(dir, prefix+"*")); err == nil {
+		for _, f := range old {
+			_ = os.Remove(f)
+		}
+	}
+	//先写临时文件再重命名，避免并发调度读到不完整的脚本
+	tmp := file + "." + Int64ToStr(param.LogID) + ".tmp"
+	if err := os.WriteFile(tmp, []byte(param.GlueSource), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// jobLogWriter 按行写入执行日志
+type jobLogWriter struct {
+	mu  sync.Mutex
+	l   *JobLogger
+	buf []byte
+}
+
+func (w *jobLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.l.Info("%s", strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 写入剩余不完整的一行
+func (w *jobLogWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.l.Info("%s", string(w.buf))
+		w.buf = nil
+	}
+}
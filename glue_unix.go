@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package xxl
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 脚本在独立的进程组中运行
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup 结束脚本及其创建的所有子进程
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
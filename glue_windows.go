@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package xxl
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup windows下通过taskkill /T结束进程树，无需设置
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// killProcessGroup 结束脚本及其创建的所有子进程
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+		_ = cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,32 @@
+package xxl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+/**
+忙碌检测，路由策略为BUSYOVER时调度中心依次调用执行器的/idleBeat，选择第一个空闲的执行器
+*/
+
+// IdleCheck 自定义忙碌检测，如数据库连接池耗尽时返回错误，jobID为要调度的任务
+type IdleCheck func(cxt context.Context, jobID int64) error
+
+// idle 执行器能否立即执行该任务，忙碌时返回原因
+func (e *executor) idle(cxt context.Context, jobID int64) error {
+	if e.runList.Exists(Int64ToStr(jobID)) {
+		if queued := e.queue.Len(Int64ToStr(jobID)); queued > 0 {
+			return fmt.Errorf("Task is busy, %d triggers in the serial queue", queued)
+		}
+		return errors.New("Task is busy")
+	}
+	if e.pool.saturated() {
+		running, waiting := e.pool.stats()
+		return fmt.Errorf("Executor is busy, %d tasks running, %d waiting", running, waiting)
+	}
+	if e.opts.idleCheck != nil {
+		return e.opts.idleCheck(cxt, jobID)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package xxl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/**
+任务执行日志，每次调度一个文件：LogDir/yyyy-MM-dd/<logId>.log
+*/
+
+const (
+	jobLogStartMark = "----------- xxl-job job execute start -----------"
+	jobLogEndMark   = "----------- xxl-job job execute end(finish) -----------"
+)
+
+// jobLogFile 单次调度的执行日志文件，start时打开，end时关闭
+type jobLogFile struct {
+	path  string
+	param *RunReq
+
+	mu   sync.Mutex //同一个日志文件可能被多个协程同时写入
+	file *os.File
+}
+
+func newJobLogFile(dir string, param *RunReq) *jobLogFile {
+	return &jobLogFile{
+		path:  jobLogPath(dir, param.LogDateTime, param.LogID),
+		param: param,
+	}
+}
+
+// openJobLogs 运行中调度打开的日志文件，FileJobLogSink按路径复用
+var openJobLogs = &jobLogList{data: make(map[string]*jobLogFile)}
+
+// jobLogList 打开的日志文件列表
+type jobLogList struct {
+	mu   sync.RWMutex
+	data map[string]*jobLogFile
+}
+
+func (l *jobLogList) Get(path string) *jobLogFile {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.data[path]
+}
+
+func (l *jobLogList) Set(path string, f *jobLogFile) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.data[path] = f
+}
+
+func (l *jobLogList) CompareAndDel(path string, f *jobLogFile) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.data[path] == f {
+		delete(l.data, path)
+	}
+}
+
+// jobLogPath 日志文件路径，logDateTime为调度时间(毫秒)
+func jobLogPath(dir string, logDateTime, logID int64) string {
+	t := time.Now()
+	if logDateTime > 0 {
+		t = time.UnixMilli(logDateTime)
+	}
+	return filepath.Join(dir, t.Format("2006-01-02"), Int64ToStr(logID)+".log")
+}
+
+// Write 追加写入日志文件，文件没有打开(未start或已end)时临时打开
+func (f *jobLogFile) Write(p []byte) (n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil {
+		return f.file.Write(p)
+	}
+	return appendJobLog(f.path, p)
+}
+
+// appendJobLog 打开、追加写入并关闭日志文件
+func appendJobLog(path string, p []byte) (int, error) {
+	file, err := openJobLog(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return file.Write(p)
+}
+
+func openJobLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// writeLine 以INFO级别写入一行日志
+func (f *jobLogFile) writeLine(format string, a ...interface{}) {
+	entry := newJobLogEntry(f.param, LevelInfo, fmt.Sprintf(format, a...))
+	_, _ = f.Write([]byte(entry.String() + "\n"))
+}
+
+// start 打开日志文件并写入任务开始标记，打开失败时每次写入临时打开
+func (f *jobLogFile) start() {
+	if file, err := openJobLog(f.path); err == nil {
+		f.mu.Lock()
+		f.file = file
+		f.mu.Unlock()
+		openJobLogs.Set(f.path, f)
+	}
+	f.writeLine(jobLogStartMark)
+	f.writeLine("----------- Param: %s", f.param.ExecutorParams)
+}
+
+// end 写入任务结束标记并关闭日志文件，日志查询以此判断日志是否已全部加载
+func (f *jobLogFile) end(code int64, msg string) {
+	f.writeLine("----------- Result: handleCode=%d, handleMsg = %s", code, msg)
+	_, _ = f.Write([]byte(jobLogEndMark + "\n"))
+	openJobLogs.CompareAndDel(f.path, f)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil {
+		_ = f.file.Close()
+		f.file = nil
+	}
+}
+
+// cleanJobLogs 删除LogDir下超过days天的日期目录，只处理yyyy-MM-dd格式的目录
+func cleanJobLogs(dir string, days int, now time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	y, m, d := now.Date()
+	expire := time.Date(y, m, d-days, 0, 0, 0, 0, now.Location())
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", entry.Name(), now.Location())
+		if err != nil || !day.Before(expire) {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// logCleaner 每天清理过期的执行日志，LogRetentionDays小于等于0时不清理
+func (e *executor) logCleaner() {
+	days := e.opts.LogRetentionDays
+	if days <= 0 {
+		return
+	}
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-e.stopCh:
+			return
+		}
+		t.Reset(24 * time.Hour)
+		removed, err := cleanJobLogs(e.opts.LogDir, days, time.Now())
+		if err != nil {
+			e.log.Error("清理执行日志失败", "err", err)
+		}
+		if removed > 0 {
+			e.log.Info("清理过期执行日志", "days", days, "removed", removed)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package xxl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JobLogLevel 执行日志级别
+type JobLogLevel int
+
+const (
+	LevelDebug JobLogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l JobLogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// JobLogEntry 一条执行日志
+type JobLogEntry struct {
+	Time        time.Time
+	Level       JobLogLevel
+	JobID       int64
+	LogID       int64
+	LogDateTime int64
+	Handler     string
+	Msg         string
+}
+
+func newJobLogEntry(param *RunReq, level JobLogLevel, msg string) *JobLogEntry {
+	return &JobLogEntry{
+		Time:        time.Now(),
+		Level:       level,
+		JobID:       param.JobID,
+		LogID:       param.LogID,
+		LogDateTime: param.LogDateTime,
+		Handler:     handlerName(param),
+		Msg:         strings.TrimRight(msg, "\n"),
+	}
+}
+
+// String 日志行格式
+func (e *JobLogEntry) String() string {
+	return fmt.Sprintf("%s [%s] [jobId=%d logId=%d handler=%s] %s",
+		e.Time.Format("2006-01-02 15:04:05"), e.Level, e.JobID, e.LogID, e.Handler, e.Msg)
+}
+
+// JobLogSink 执行日志输出
+type JobLogSink interface {
+	WriteJobLog(entry *JobLogEntry)
+}
+
+// FileJobLogSink 写入LogDir下的执行日志文件，可在后台"执行日志"中查看
+func FileJobLogSink(dir string) JobLogSink {
+	return &fileJobLogSink{dir: dir}
+}
+
+type fileJobLogSink struct {
+	dir string
+}
+
+func (s *fileJobLogSink) WriteJobLog(entry *JobLogEntry) {
+	path := jobLogPath(s.dir, entry.LogDateTime, entry.LogID)
+	if f := openJobLogs.Get(path); f != nil {
+		_, _ = f.Write([]byte(entry.String() + "\n"))
+		return
+	}
+	_, _ = appendJobLog(path, []byte(entry.String()+"\n"))
+}
+
+// LoggerJobLogSink 写入系统日志，DEBUG/INFO使用Info，WARN/ERROR使用Error
+func LoggerJobLogSink(l Logger) JobLogSink {
+	return StructuredJobLogSink(FromLogger(l))
+}
+
+// StructuredJobLogSink 写入结构化系统日志，附带job_id、log_id、handler字段
+func StructuredJobLogSink(l StructuredLogger) JobLogSink {
+	return &structuredJobLogSink{l: l}
+}
+
+type structuredJobLogSink struct {
+	l StructuredLogger
+}
+
+func (s *structuredJobLogSink) WriteJobLog(entry *JobLogEntry) {
+	kv := []interface{}{FieldJobID, entry.JobID, FieldLogID, entry.LogID, FieldHandler, entry.Handler}
+	switch entry.Level {
+	case LevelDebug:
+		s.l.Debug(entry.Msg, kv...)
+	case LevelWarn:
+		s.l.Warn(entry.Msg, kv...)
+	case LevelError:
+		s.l.Error(entry.Msg, kv...)
+	default:
+		s.l.Info(entry.Msg, kv...)
+	}
+}
+
+// MultiJobLogSink 同时写入多个输出
+func MultiJobLogSink(sinks ...JobLogSink) JobLogSink {
+	return multiJobLogSink(sinks)
+}
+
+type multiJobLogSink []JobLogSink
+
+func (m multiJobLogSink) WriteJobLog(entry *JobLogEntry) {
+	for _, s := range m {
+		s.WriteJobLog(entry)
+	}
+}
+
+type jobLoggerKey struct{}
+
+// JobLogger 当前调度的执行日志，每行都带有JobID、LogID和任务名称
+type JobLogger struct {
+	sink  JobLogSink
+	param *RunReq
+}
+
+func newJobLogger(sink JobLogSink, param *RunReq) *JobLogger {
+	return &JobLogger{sink: sink, param: param}
+}
+
+// GetJobLogger 从TaskFunc的context中获取执行日志，不在任务中时返回的JobLogger不输出任何内容
+func GetJobLogger(cxt context.Context) *JobLogger {
+	if l, ok := cxt.Value(jobLoggerKey{}).(*JobLogger); ok && l != nil {
+		return l
+	}
+	return &JobLogger{}
+}
+
+func withJobLogger(cxt context.Context, l *JobLogger) context.Context {
+	return context.WithValue(cxt, jobLoggerKey{}, l)
+}
+
+func (l *JobLogger) log(level JobLogLevel, format string, a ...interface{}) {
+	if l.sink == nil || l.param == nil {
+		return
+	}
+	l.sink.WriteJobLog(newJobLogEntry(l.param, level, fmt.Sprintf(format, a...)))
+}
+
+// Debug 调试日志
+func (l *JobLogger) Debug(format string, a ...interface{}) {
+	l.log(LevelDebug, format, a...)
+}
+
+// Info 信息日志
+func (l *JobLogger) Info(format string, a ...interface{}) {
+	l.log(LevelInfo, format, a...)
+}
+
+// Warn 警告日志
+func (l *JobLogger) Warn(format string, a ...interface{}) {
+	l.log(LevelWarn, format, a...)
+}
+
+// Error 错误日志
+func (l *JobLogger) Error(format string, a ...interface{}) {
+	l.log(LevelError, format, a...)
+}
+
+// JobLog 写入当前调度的执行日志，等同于GetJobLogger(cxt).Info
+func JobLog(cxt context.Context, format string, a ...interface{}) {
+	GetJobLogger(cxt).Info(format, a...)
+}
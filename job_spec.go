@@ -0,0 +1,211 @@
+package xxl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+调度中心任务定义，只注册handler时调度中心不会自动创建任务，
+通过WithJobSpec声明后，设置了超管密码(AdminPwd)的执行器会在注册时创建或更新调度中心的任务。
+只同步声明了的字段：字符串为空、指针和切片为nil视为未声明，调度中心已有任务的未声明字段保持不变，
+新建任务时未声明的字段使用默认值
+*/
+
+// 调度类型
+const (
+	ScheduleNone    = "NONE"     //不自动调度，只能手动触发或由父任务触发
+	ScheduleCron    = "CRON"     //CRON表达式
+	ScheduleFixRate = "FIX_RATE" //固定间隔，单位秒
+)
+
+// 路由策略
+const (
+	RouteFirst              = "FIRST"                 //第一个
+	RouteLast               = "LAST"                  //最后一个
+	RouteRound              = "ROUND"                 //轮询
+	RouteRandom             = "RANDOM"                //随机
+	RouteConsistentHash     = "CONSISTENT_HASH"       //一致性HASH
+	RouteLeastFrequentlyUse = "LEAST_FREQUENTLY_USED" //最不经常使用
+	RouteLeastRecentlyUsed  = "LEAST_RECENTLY_USED"   //最近最久未使用
+	RouteFailover           = "FAILOVER"              //故障转移
+	RouteBusyover           = "BUSYOVER"              //忙碌转移
+	RouteShardingBroadcast  = "SHARDING_BROADCAST"    //分片广播
+)
+
+// 调度过期策略
+const (
+	MisfireDoNothing   = "DO_NOTHING"    //忽略
+	MisfireFireOnceNow = "FIRE_ONCE_NOW" //立即执行一次
+)
+
+// 阻塞处理策略
+const (
+	BlockSerialExecution = serialExecution //单机串行
+	BlockDiscardLater    = discardLater    //丢弃后续调度
+	BlockCoverEarly      = coverEarly      //覆盖之前调度
+)
+
+// 新建任务时未声明字段的默认值
+const (
+	defaultJobAuthor     = "beagle"
+	defaultJobGlueRemark = "GLUE代码初始化"
+)
+
+// JobSpec 调度中心任务定义
+type JobSpec struct {
+	Desc            string //任务描述，新建时默认为任务标识
+	Author          string //负责人
+	AlarmEmail      string //报警邮件，多个用逗号分隔
+	ScheduleType    string //调度类型，新建时默认为NONE
+	ScheduleConf    string //调度配置，CRON时为CRON表达式，FIX_RATE时为间隔秒数
+	RouteStrategy   string //路由策略，新建时默认为FIRST
+	MisfireStrategy string //调度过期策略，新建时默认为DO_NOTHING
+	BlockStrategy   string //阻塞处理策略，新建时默认为SERIAL_EXECUTION
+	Timeout         *int   //任务超时时间，单位秒，0为不限制
+	RetryCount      *int   //失败重试次数
+	ChildJobIDs     []int  //子任务Id，空切片表示清空子任务
+	Params          string //任务参数，为空时不修改
+}
+
+// WithJobSpec 声明调度中心任务，注册时同步到调度中心
+func WithJobSpec(spec JobSpec) TaskOption {
+	return func(h *jobHandler) {
+		h.spec = &spec
+	}
+}
+
+// cronSpec RegTask、RegJob的任务定义，没有调度配置时不修改调度中心的调度配置
+func cronSpec(desc, scheduleConf string) JobSpec {
+	if strings.TrimSpace(scheduleConf) == "" {
+		return JobSpec{Desc: desc}
+	}
+	return JobSpec{Desc: desc, ScheduleType: ScheduleCron, ScheduleConf: scheduleConf}
+}
+
+// validate 校验声明的字段
+func (s *JobSpec) validate() error {
+	switch s.ScheduleType {
+	case "", ScheduleNone:
+	case ScheduleCron:
+		if strings.TrimSpace(s.ScheduleConf) == "" {
+			return errors.New("CRON表达式不能为空")
+		}
+	case ScheduleFixRate:
+		if n, err := strconv.Atoi(s.ScheduleConf); err != nil || n <= 0 {
+			return fmt.Errorf("FIX_RATE间隔秒数[%s]无效", s.ScheduleConf)
+		}
+	default:
+		return fmt.Errorf("调度类型[%s]无效", s.ScheduleType)
+	}
+	if s.ScheduleType == "" && s.ScheduleConf != "" {
+		return errors.New("声明调度配置时必须声明调度类型")
+	}
+	switch s.RouteStrategy {
+	case "", RouteFirst, RouteLast, RouteRound, RouteRandom, RouteConsistentHash, RouteLeastFrequentlyUse,
+		RouteLeastRecentlyUsed, RouteFailover, RouteBusyover, RouteShardingBroadcast:
+	default:
+		return fmt.Errorf("路由策略[%s]无效", s.RouteStrategy)
+	}
+	switch s.MisfireStrategy {
+	case "", MisfireDoNothing, MisfireFireOnceNow:
+	default:
+		return fmt.Errorf("调度过期策略[%s]无效", s.MisfireStrategy)
+	}
+	switch s.BlockStrategy {
+	case "", BlockSerialExecution, BlockDiscardLater, BlockCoverEarly:
+	default:
+		return fmt.Errorf("阻塞处理策略[%s]无效", s.BlockStrategy)
+	}
+	if s.Timeout != nil && *s.Timeout < 0 {
+		return fmt.Errorf("任务超时时间[%d]无效", *s.Timeout)
+	}
+	if s.RetryCount != nil && *s.RetryCount < 0 {
+		return fmt.Errorf("失败重试次数[%d]无效", *s.RetryCount)
+	}
+	return nil
+}
+
+// newJob 新建任务，未声明的字段使用默认值
+func (s *JobSpec) newJob(executorHandler string) xxlJob {
+	job := xxlJob{
+		JobDesc:                executorHandler,
+		Author:                 defaultJobAuthor,
+		ScheduleType:           ScheduleNone,
+		MisfireStrategy:        MisfireDoNothing,
+		ExecutorRouteStrategy:  RouteFirst,
+		ExecutorHandler:        executorHandler,
+		ExecutorBlockStrategy:  BlockSerialExecution,
+		GlueType:               glueBean,
+		GlueRemark:             defaultJobGlueRemark,
+		ExecutorTimeout:        0,
+		ExecutorFailRetryCount: 0,
+	}
+	s.apply(&job)
+	return job
+}
+
+// apply 将声明的字段覆盖到任务上，返回是否有修改
+func (s *JobSpec) apply(job *xxlJob) bool {
+	changed := false
+	setString := func(dst *string, v string) {
+		if v != "" && *dst != v {
+			*dst = v
+			changed = true
+		}
+	}
+	setInt := func(dst *int, v *int) {
+		if v != nil && *dst != *v {
+			*dst = *v
+			changed = true
+		}
+	}
+	setString(&job.JobDesc, s.Desc)
+	setString(&job.Author, s.Author)
+	setString(&job.AlarmEmail, s.AlarmEmail)
+	if s.ScheduleType != "" && (job.ScheduleType != s.ScheduleType || job.ScheduleConf != s.ScheduleConf) {
+		job.ScheduleType, job.ScheduleConf = s.ScheduleType, s.ScheduleConf
+		changed = true
+	}
+	setString(&job.ExecutorRouteStrategy, s.RouteStrategy)
+	setString(&job.MisfireStrategy, s.MisfireStrategy)
+	setString(&job.ExecutorBlockStrategy, s.BlockStrategy)
+	setInt(&job.ExecutorTimeout, s.Timeout)
+	setInt(&job.ExecutorFailRetryCount, s.RetryCount)
+	if s.ChildJobIDs != nil {
+		ids := make([]string, 0, len(s.ChildJobIDs))
+		for _, id := range s.ChildJobIDs {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		setString(&job.ChildJobId, strings.Join(ids, ","))
+		if len(ids) == 0 && job.ChildJobId != "" {
+			job.ChildJobId = ""
+			changed = true
+		}
+	}
+	setString(&job.ExecutorParam, s.Params)
+	return changed
+}
+
+// provision 将任务定义同步到调度中心，没有设置超管密码时跳过
+func (e *executor) provision(h *jobHandler) error {
+	if h.spec == nil {
+		return nil
+	}
+	if e.opts.AdminPwd == "" {
+		e.log.Debug("未设置超管密码，跳过任务同步", FieldHandler, h.name)
+		return nil
+	}
+	err := h.spec.validate()
+	if err != nil {
+		err = fmt.Errorf("任务[%s]定义无效: %w", h.name, err)
+	} else {
+		err = e.xxl.checkOrAddJob(h.name, *h.spec)
+	}
+	if err != nil {
+		e.log.Error("同步任务失败", FieldHandler, h.name, "err", err)
+	}
+	return err
+}
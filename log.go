@@ -3,6 +3,7 @@ package xxl
 import (
 	"fmt"
 	"log"
+	"strings"
 )
 
 // LogFunc 应用日志
@@ -24,3 +25,89 @@ func (l *logger) Info(format string, a ...interface{}) {
 func (l *logger) Error(format string, a ...interface{}) {
 	log.Println(fmt.Sprintf(format, a...))
 }
+
+// 结构化日志中的任务字段
+const (
+	FieldJobID   = "job_id"
+	FieldLogID   = "log_id"
+	FieldHandler = "handler"
+)
+
+// StructuredLogger 结构化分级系统日志，kv为成对的字段名和值
+type StructuredLogger interface {
+	Debug(msg string, kv ...interface{})
+	Info(msg string, kv ...interface{})
+	Warn(msg string, kv ...interface{})
+	Error(msg string, kv ...interface{})
+	// With 返回附带固定字段的日志
+	With(kv ...interface{}) StructuredLogger
+}
+
+// FromLogger 将printf风格的Logger适配为StructuredLogger，字段以key=value追加在消息后，
+// DEBUG/INFO使用Info，WARN/ERROR使用Error
+func FromLogger(l Logger) StructuredLogger {
+	return &loggerAdapter{l: l}
+}
+
+type loggerAdapter struct {
+	l      Logger
+	fields []interface{}
+}
+
+func (a *loggerAdapter) Debug(msg string, kv ...interface{}) {
+	a.l.Info("%s", a.format("DEBUG", msg, kv))
+}
+
+func (a *loggerAdapter) Info(msg string, kv ...interface{}) {
+	a.l.Info("%s", a.format("", msg, kv))
+}
+
+func (a *loggerAdapter) Warn(msg string, kv ...interface{}) {
+	a.l.Error("%s", a.format("WARN", msg, kv))
+}
+
+func (a *loggerAdapter) Error(msg string, kv ...interface{}) {
+	a.l.Error("%s", a.format("", msg, kv))
+}
+
+func (a *loggerAdapter) With(kv ...interface{}) StructuredLogger {
+	fields := make([]interface{}, 0, len(a.fields)+len(kv))
+	fields = append(fields, a.fields...)
+	fields = append(fields, kv...)
+	return &loggerAdapter{l: a.l, fields: fields}
+}
+
+// format Logger只有Info和Error，DEBUG和WARN在消息前标注级别
+func (a *loggerAdapter) format(level, msg string, kv []interface{}) string {
+	var b strings.Builder
+	if level != "" {
+		b.WriteString("[" + level + "] ")
+	}
+	b.WriteString(msg)
+	writeFields(&b, a.fields)
+	writeFields(&b, kv)
+	return b.String()
+}
+
+func writeFields(b *strings.Builder, kv []interface{}) {
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 < len(kv) {
+			fmt.Fprintf(b, " %v=%v", kv[i], kv[i+1])
+		} else {
+			fmt.Fprintf(b, " %v", kv[i])
+		}
+	}
+}
+
+// jobFields 调度参数对应的日志字段
+func jobFields(param *RunReq) []interface{} {
+	return []interface{}{FieldJobID, param.JobID, FieldLogID, param.LogID, FieldHandler, handlerName(param)}
+}
+
+// handlerName 任务名称，GLUE脚本任务没有JobHandler，使用GLUE类型
+func handlerName(param *RunReq) string {
+	if param.ExecutorHandler == "" {
+		return param.GlueType
+	}
+	return param.ExecutorHandler
+}
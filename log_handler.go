@@ -1,8 +1,12 @@
 package xxl
 
 import (
+	"bufio"
 	"encoding/json"
+	"io"
 	"net/http"
+	"os"
+	"strings"
 )
 
 /**
@@ -11,13 +15,67 @@ import (
 
 type LogHandler func(req *LogReq) *LogRes
 
-//默认返回
-func defaultLogHandler(req *LogReq) *LogRes {
+// 单次日志查询最多返回的行数
+const logPageLines = 1000
+
+// FileLogHandler 读取LogDir下的任务执行日志，按FromLineNum分页返回
+func FileLogHandler(dir string) LogHandler {
+	return func(req *LogReq) *LogRes {
+		return readJobLog(jobLogPath(dir, req.LogDateTim, req.LogID), req.FromLineNum)
+	}
+}
+
+// 从fromLineNum(从1开始)读取日志文件
+func readJobLog(path string, fromLineNum int) *LogRes {
+	if fromLineNum < 1 {
+		fromLineNum = 1
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		msg := "readLog fail, logFile not exists"
+		if !os.IsNotExist(err) {
+			msg = "readLog fail, " + err.Error()
+		}
+		return &LogRes{Code: SuccessCode, Msg: "", Content: LogResContent{
+			FromLineNum: fromLineNum,
+			ToLineNum:   0,
+			LogContent:  msg,
+			IsEnd:       true,
+		}}
+	}
+	defer file.Close()
+
+	var (
+		content  strings.Builder
+		lineNum  int
+		lastLine string
+		eof      bool
+	)
+	toLineNum := fromLineNum - 1
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lineNum++
+			lastLine = strings.TrimRight(line, "\r\n")
+			if lineNum >= fromLineNum {
+				content.WriteString(line)
+				toLineNum = lineNum
+			}
+		}
+		if err != nil {
+			eof = err == io.EOF
+			break
+		}
+		if toLineNum-fromLineNum+1 >= logPageLines {
+			break
+		}
+	}
 	return &LogRes{Code: SuccessCode, Msg: "", Content: LogResContent{
-		FromLineNum: req.FromLineNum,
-		ToLineNum:   2,
-		LogContent:  "这是日志默认返回，说明没有设置LogHandler",
-		IsEnd:       true,
+		FromLineNum: fromLineNum,
+		ToLineNum:   toLineNum,
+		LogContent:  content.String(),
+		IsEnd:       eof && lastLine == jobLogEndMark,
 	}}
 }
 
@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package xxl
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger 将log/slog适配为StructuredLogger
+func SlogLogger(l *slog.Logger) StructuredLogger {
+	return &slogAdapter{l: l}
+}
+
+type slogAdapter struct {
+	l *slog.Logger
+}
+
+func (a *slogAdapter) Debug(msg string, kv ...interface{}) {
+	a.l.Log(context.Background(), slog.LevelDebug, msg, kv...)
+}
+
+func (a *slogAdapter) Info(msg string, kv ...interface{}) {
+	a.l.Log(context.Background(), slog.LevelInfo, msg, kv...)
+}
+
+func (a *slogAdapter) Warn(msg string, kv ...interface{}) {
+	a.l.Log(context.Background(), slog.LevelWarn, msg, kv...)
+}
+
+func (a *slogAdapter) Error(msg string, kv ...interface{}) {
+	a.l.Log(context.Background(), slog.LevelError, msg, kv...)
+}
+
+func (a *slogAdapter) With(kv ...interface{}) StructuredLogger {
+	return &slogAdapter{l: a.l.With(kv...)}
+}
@@ -0,0 +1,182 @@
+package xxl
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+执行器监控指标，/metrics 输出Prometheus文本格式，不依赖Prometheus客户端
+*/
+
+// 任务耗时直方图的桶，单位秒
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 600, 1800, 3600}
+
+type histogram struct {
+	counts []uint64 //每个桶的累计数量
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range durationBuckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type runKey struct {
+	handler string
+	outcome string
+}
+
+// metrics 执行器监控统计
+type metrics struct {
+	mu               sync.Mutex
+	triggers         map[string]uint64     //[handler]收到的调度
+	runs             map[runKey]uint64     //[handler,outcome]执行结果
+	durations        map[string]*histogram //[handler]执行耗时
+	rejected         map[string]uint64     //[阻塞策略]拒绝的调度
+	callbackFailures uint64
+	registrySuccess  uint64
+	registryFailure  uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		triggers:  make(map[string]uint64),
+		runs:      make(map[runKey]uint64),
+		durations: make(map[string]*histogram),
+		rejected:  make(map[string]uint64),
+	}
+}
+
+func (m *metrics) trigger(handler string) {
+	m.mu.Lock()
+	m.triggers[handler]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) run(handler, outcome string, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[runKey{handler: handler, outcome: outcome}]++
+	h, ok := m.durations[handler]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(durationBuckets))}
+		m.durations[handler] = h
+	}
+	h.observe(seconds)
+}
+
+func (m *metrics) reject(strategy string) {
+	m.mu.Lock()
+	m.rejected[strategy]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) callbackFailed() {
+	m.mu.Lock()
+	m.callbackFailures++
+	m.mu.Unlock()
+}
+
+func (m *metrics) registry(ok bool) {
+	m.mu.Lock()
+	if ok {
+		m.registrySuccess++
+	} else {
+		m.registryFailure++
+	}
+	m.mu.Unlock()
+}
+
+// write 输出Prometheus文本格式，running为当前运行中的任务数，queued为串行队列中和等待并发空闲的任务数
+func (m *metrics) write(w io.Writer, running, queued int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeHeader(w, "xxl_job_executor_triggers_total", "counter", "Triggers received per handler.")
+	for _, handler := range sortedKeys(m.triggers) {
+		fmt.Fprintf(w, "xxl_job_executor_triggers_total{handler=%s} %d\n", quoteLabel(handler), m.triggers[handler])
+	}
+
+	writeHeader(w, "xxl_job_executor_runs_total", "counter", "Finished runs per handler and outcome.")
+	keys := make([]runKey, 0, len(m.runs))
+	for k := range m.runs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].handler != keys[j].handler {
+			return keys[i].handler < keys[j].handler
+		}
+		return keys[i].outcome < keys[j].outcome
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "xxl_job_executor_runs_total{handler=%s,outcome=%s} %d\n", quoteLabel(k.handler), quoteLabel(k.outcome), m.runs[k])
+	}
+
+	writeHeader(w, "xxl_job_executor_run_duration_seconds", "histogram", "Run duration per handler in seconds.")
+	for _, handler := range sortedKeys(m.durations) {
+		h := m.durations[handler]
+		label := quoteLabel(handler)
+		for i, b := range durationBuckets {
+			fmt.Fprintf(w, "xxl_job_executor_run_duration_seconds_bucket{handler=%s,le=\"%s\"} %d\n", label, strconv.FormatFloat(b, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "xxl_job_executor_run_duration_seconds_bucket{handler=%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(w, "xxl_job_executor_run_duration_seconds_sum{handler=%s} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "xxl_job_executor_run_duration_seconds_count{handler=%s} %d\n", label, h.count)
+	}
+
+	writeHeader(w, "xxl_job_executor_running_tasks", "gauge", "Tasks currently running.")
+	fmt.Fprintf(w, "xxl_job_executor_running_tasks %d\n", running)
+
+	writeHeader(w, "xxl_job_executor_queued_tasks", "gauge", "Triggers waiting in serial execution queues or for a free worker.")
+	fmt.Fprintf(w, "xxl_job_executor_queued_tasks %d\n", queued)
+
+	writeHeader(w, "xxl_job_executor_rejected_triggers_total", "counter", "Triggers rejected per block strategy or concurrency limit.")
+	for _, strategy := range sortedKeys(m.rejected) {
+		fmt.Fprintf(w, "xxl_job_executor_rejected_triggers_total{strategy=%s} %d\n", quoteLabel(strategy), m.rejected[strategy])
+	}
+
+	writeHeader(w, "xxl_job_executor_callback_failures_total", "counter", "Failed callback requests to the scheduling center.")
+	fmt.Fprintf(w, "xxl_job_executor_callback_failures_total %d\n", m.callbackFailures)
+
+	writeHeader(w, "xxl_job_executor_registry_heartbeats_total", "counter", "Registry heartbeats by result.")
+	fmt.Fprintf(w, "xxl_job_executor_registry_heartbeats_total{result=\"success\"} %d\n", m.registrySuccess)
+	fmt.Fprintf(w, "xxl_job_executor_registry_heartbeats_total{result=\"failure\"} %d\n", m.registryFailure)
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelReplacer.Replace(v) + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 监控指标
+func (e *executor) metricsHandler(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	running, waiting := e.pool.stats()
+	e.metrics.write(writer, running, e.queue.Size()+waiting)
+}
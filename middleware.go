@@ -0,0 +1,45 @@
+package xxl
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// Middleware 任务中间件，在next前后处理RunReq、context和执行结果，用法同net/http中间件
+type Middleware func(next JobFunc) JobFunc
+
+// chainMiddleware 按顺序包装任务，第一个中间件在最外层
+func chainMiddleware(fn JobFunc, mws ...Middleware) JobFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		fn = mws[i](fn)
+	}
+	return fn
+}
+
+// PanicError 任务panic
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panic:%v", e.Value)
+}
+
+// Recovery 捕获任务panic，记录堆栈并返回PanicError，执行器默认作为第一个中间件
+func Recovery(l StructuredLogger) Middleware {
+	return func(next JobFunc) JobFunc {
+		return func(cxt context.Context, param *RunReq) (msg string, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					stack := debug.Stack()
+					l.Error("任务panic", append(jobFields(param), "panic", r, "stack", string(stack))...)
+					GetJobLogger(cxt).Error("panic: %v\n%s", r, stack)
+					err = &PanicError{Value: r, Stack: stack}
+				}
+			}()
+			return next(cxt, param)
+		}
+	}
+}
@@ -1,24 +1,62 @@
 package xxl
 
 import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/go-basic/ipv4"
 )
 
 type Options struct {
-	ServerAddr    string        `json:"server_addr"`    //调度中心地址
+	ServerAddr    string        `json:"server_addr"`    //调度中心地址，多个地址逗号分隔
 	AccessToken   string        `json:"access_token"`   //请求令牌
-	Timeout       time.Duration `json:"timeout"`        //接口超时时间
+	Timeout       time.Duration `json:"timeout"`        //接口超时时间，小于等于0时为10秒
 	ExecutorIp    string        `json:"executor_ip"`    //本地(执行器)IP(可自行获取)
 	ExecutorPort  string        `json:"executor_port"`  //本地(执行器)端口
 	RegistryKey   string        `json:"registry_key"`   //执行器名称
 	RegistryAlias string        `json:"registry_alias"` // 执行器别名
 	LogDir        string        `json:"log_dir"`        //日志目录
+	AdminUser     string        `json:"admin_user"`     // 超管用户名
 	AdminPwd      string        `json:"admin_pwd"`      // 超管密码
 	AddressList   string        `json:"address_list"`   //机器地址
+	//执行日志保留天数，每天清理LogDir下更早的日期目录，小于等于0不清理
+	LogRetentionDays int `json:"log_retention_days"`
+	//单机串行队列最大深度，小于等于0不限制
+	SerialQueueSize int `json:"serial_queue_size"`
+	//停止时等待运行中任务结束的最长时间，超时后取消任务并回调失败
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+	//是否开启/metrics监控指标
+	EnableMetrics bool `json:"enable_metrics"`
+	//是否开启/status/tasks、/status/running状态查询
+	EnableStatus bool `json:"enable_status"`
+	//多个调度中心地址的选择策略，AdminRouteFailover或AdminRouteRoundRobin
+	AdminRoute string `json:"admin_route"`
+	//执行器同时运行的最大任务数，小于等于0不限制
+	MaxConcurrency int `json:"max_concurrency"`
+	//并发已满时的处理策略，OverflowReject或OverflowQueue
+	OverflowPolicy string `json:"overflow_policy"`
+	//OverflowQueue时等待并发空闲的最大调度数，小于等于0不限制
+	PoolQueueSize int `json:"pool_queue_size"`
+	//HTTPS证书和私钥文件，设置后执行器使用HTTPS，注册地址为https
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	//客户端CA证书文件，设置后要求调度中心提供该CA签发的客户端证书
+	TLSClientCAFile string `json:"tls_client_ca_file"`
+	//注册地址的路径，执行器挂载到已有http服务的子路径时设置，如/xxl-job/
+	AddressPath string `json:"address_path"`
+	//Init时即开始注册，兼容只调用Init、自行挂载RunTask等路由的用法；
+	//默认由Start、Run或Register开始注册，避免服务监听前调度中心就调度过来
+	RegistryOnInit bool `json:"registry_on_init"`
+	//是否允许执行GLUE脚本任务，开启时必须设置AccessToken
+	EnableGlue bool `json:"enable_glue"`
 
-	l Logger //日志处理
+	l         Logger           //日志处理
+	sl        StructuredLogger //结构化日志处理，设置后优先于l
+	logSink   JobLogSink       //执行日志输出
+	idleCheck IdleCheck        //忙碌检测
+	tlsConfig *tls.Config      //HTTPS配置
 }
 
 func newOptions(opts ...Option) Options {
@@ -26,6 +64,15 @@ func newOptions(opts ...Option) Options {
 		ExecutorIp:   ipv4.LocalIP(),
 		ExecutorPort: DefaultExecutorPort,
 		RegistryKey:  DefaultRegistryKey,
+		LogDir:       DefaultLogDir,
+		AdminUser:    DefaultAdminUser,
+
+		LogRetentionDays: DefaultLogRetentionDays,
+		SerialQueueSize:  DefaultSerialQueueSize,
+		ShutdownTimeout:  DefaultShutdownTimeout,
+		AdminRoute:       AdminRouteFailover,
+		OverflowPolicy:   OverflowReject,
+		PoolQueueSize:    DefaultPoolQueueSize,
 	}
 
 	for _, o := range opts {
@@ -44,15 +91,29 @@ type Option func(o *Options)
 var (
 	DefaultExecutorPort = "9999"
 	DefaultRegistryKey  = "golang-jobs"
+	DefaultLogDir       = filepath.Join(os.TempDir(), "xxl-job", "jobhandler")
+	DefaultAdminUser    = "admin"
+
+	DefaultLogRetentionDays = 30
+	DefaultSerialQueueSize  = 100
+	DefaultShutdownTimeout  = 30 * time.Second
+	DefaultPoolQueueSize    = 1000
 )
 
-// ServerAddr 设置调度中心地址
+// ServerAddr 设置调度中心地址，多个地址逗号分隔
 func ServerAddr(addr string) Option {
 	return func(o *Options) {
 		o.ServerAddr = addr
 	}
 }
 
+// SetAdminRoute 设置多个调度中心地址的选择策略，默认AdminRouteFailover
+func SetAdminRoute(route string) Option {
+	return func(o *Options) {
+		o.AdminRoute = route
+	}
+}
+
 // AccessToken 请求令牌
 func AccessToken(token string) Option {
 	return func(o *Options) {
@@ -81,6 +142,69 @@ func RegistryKey(registryKey string) Option {
 	}
 }
 
+// SetLogDir 设置任务执行日志目录
+func SetLogDir(dir string) Option {
+	return func(o *Options) {
+		o.LogDir = dir
+	}
+}
+
+// SetSerialQueueSize 设置单机串行队列最大深度，小于等于0不限制
+func SetSerialQueueSize(size int) Option {
+	return func(o *Options) {
+		o.SerialQueueSize = size
+	}
+}
+
+// SetShutdownTimeout 设置停止时等待运行中任务结束的最长时间
+func SetShutdownTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.ShutdownTimeout = timeout
+	}
+}
+
+// SetMetrics 设置是否开启/metrics监控指标
+func SetMetrics(enable bool) Option {
+	return func(o *Options) {
+		o.EnableMetrics = enable
+	}
+}
+
+// SetStatus 设置是否开启/status/tasks、/status/running状态查询
+func SetStatus(enable bool) Option {
+	return func(o *Options) {
+		o.EnableStatus = enable
+	}
+}
+
+// SetRegistryOnInit 设置是否在Init时开始注册，自行挂载路由且不调用Register时开启
+func SetRegistryOnInit(enable bool) Option {
+	return func(o *Options) {
+		o.RegistryOnInit = enable
+	}
+}
+
+// SetGlueEnabled 设置是否允许执行GLUE脚本任务，脚本由调度中心下发并在本机执行，开启时必须设置AccessToken
+func SetGlueEnabled(enable bool) Option {
+	return func(o *Options) {
+		o.EnableGlue = enable
+	}
+}
+
+// SetLogRetentionDays 设置执行日志保留天数，小于等于0不清理
+func SetLogRetentionDays(days int) Option {
+	return func(o *Options) {
+		o.LogRetentionDays = days
+	}
+}
+
+// SetJobLogSink 设置执行日志输出，默认写入LogDir
+func SetJobLogSink(sink JobLogSink) Option {
+	return func(o *Options) {
+		o.logSink = sink
+	}
+}
+
 // SetLogger 设置日志处理器
 func SetLogger(l Logger) Option {
 	return func(o *Options) {
@@ -88,6 +212,20 @@ func SetLogger(l Logger) Option {
 	}
 }
 
+// SetStructuredLogger 设置结构化日志处理器，优先于SetLogger
+func SetStructuredLogger(l StructuredLogger) Option {
+	return func(o *Options) {
+		o.sl = l
+	}
+}
+
+// SetAdminUser 设置超管用户名
+func SetAdminUser(user string) Option {
+	return func(o *Options) {
+		o.AdminUser = user
+	}
+}
+
 // SetAdminPwd 设置超管密码
 func SetAdminPwd(pwd string) Option {
 	return func(o *Options) {
@@ -108,3 +246,60 @@ func SetAddressList(address string) Option {
 		o.AddressList = address
 	}
 }
+
+// SetMaxConcurrency 设置执行器同时运行的最大任务数，小于等于0不限制
+func SetMaxConcurrency(n int) Option {
+	return func(o *Options) {
+		o.MaxConcurrency = n
+	}
+}
+
+// SetOverflowPolicy 设置并发已满时的处理策略，OverflowReject拒绝调度，OverflowQueue排队等待
+func SetOverflowPolicy(policy string) Option {
+	return func(o *Options) {
+		o.OverflowPolicy = policy
+	}
+}
+
+// SetPoolQueueSize 设置等待并发空闲的最大调度数，小于等于0不限制
+func SetPoolQueueSize(size int) Option {
+	return func(o *Options) {
+		o.PoolQueueSize = size
+	}
+}
+
+// SetIdleCheck 设置忙碌检测，返回错误时idleBeat返回忙碌，错误信息为忙碌原因
+func SetIdleCheck(check IdleCheck) Option {
+	return func(o *Options) {
+		o.idleCheck = check
+	}
+}
+
+// SetTLS 设置HTTPS证书和私钥文件
+func SetTLS(certFile, keyFile string) Option {
+	return func(o *Options) {
+		o.TLSCertFile = certFile
+		o.TLSKeyFile = keyFile
+	}
+}
+
+// SetTLSConfig 设置HTTPS配置，与SetTLS同时设置时追加证书，与SetClientCA同时设置时以SetClientCA为准
+func SetTLSConfig(cfg *tls.Config) Option {
+	return func(o *Options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// SetClientCA 设置客户端CA证书文件，开启双向TLS，需要同时设置SetTLS或SetTLSConfig
+func SetClientCA(caFile string) Option {
+	return func(o *Options) {
+		o.TLSClientCAFile = caFile
+	}
+}
+
+// SetAddressPath 设置注册地址的路径，如/xxl-job/，注册地址为http://ip:port/xxl-job/
+func SetAddressPath(path string) Option {
+	return func(o *Options) {
+		o.AddressPath = path
+	}
+}
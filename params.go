@@ -0,0 +1,348 @@
+package xxl
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+任务参数解码，ExecutorParams支持三种格式：
+JSON：{"name":"a","count":3}
+查询串：name=a&count=3
+命令行：--name=a --count 3 -verbose
+查询串和命令行按字段的param标签匹配，没有param标签时使用json标签，再没有时使用字段名，均不区分大小写；
+切片字段可重复出现或用逗号分隔，bool字段在命令行中可省略值
+*/
+
+// TypedJobFunc 带类型参数的任务执行函数，args为解码后的ExecutorParams
+type TypedJobFunc[T any] func(cxt context.Context, param *RunReq, args T) (string, error)
+
+// TypedJob 将TypedJobFunc适配为JobFunc，ExecutorParams解码到defaults的副本上，
+// 参数中未出现的字段保留默认值，解码失败时任务回调失败
+func TypedJob[T any](defaults T, fn TypedJobFunc[T]) JobFunc {
+	return func(cxt context.Context, param *RunReq) (string, error) {
+		args, err := DecodeParams(param.ExecutorParams, defaults)
+		if err != nil {
+			return "", err
+		}
+		return fn(cxt, param, args)
+	}
+}
+
+// DecodeParams 将ExecutorParams解码到defaults的深拷贝上，调用方的defaults不会被修改
+func DecodeParams[T any](params string, defaults T) (T, error) {
+	args := defaults
+	v := reflect.ValueOf(&args).Elem()
+	//并发调度共用defaults，指针、切片、map等都复制后再解码
+	v.Set(deepCopy(v))
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	params = strings.TrimSpace(params)
+	if params == "" {
+		return args, nil
+	}
+	var err error
+	var format string
+	switch {
+	case strings.HasPrefix(params, "{"):
+		format = "json"
+		err = decodeJSONParams(params, &args)
+	case strings.HasPrefix(params, "-"):
+		format = "flag"
+		err = decodeFlagParams(params, v)
+	default:
+		format = "query"
+		err = decodeQueryParams(params, v)
+	}
+	if err != nil {
+		return defaults, fmt.Errorf("ExecutorParams解析失败(%s): %w", format, err)
+	}
+	return args, nil
+}
+
+// deepCopy 递归复制指针、切片、数组、map、interface和结构体的导出字段，
+// 未导出字段无法通过反射设置，仍与原值共享
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < c.NumField(); i++ {
+			if f := c.Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+func decodeJSONParams(params string, v interface{}) error {
+	dec := json.NewDecoder(strings.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("JSON之后存在多余内容")
+	}
+	return nil
+}
+
+func decodeQueryParams(params string, v reflect.Value) error {
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		return err
+	}
+	fields, err := paramFields(v)
+	if err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(values) {
+		f, ok := fields[strings.ToLower(key)]
+		if !ok {
+			return fmt.Errorf("未知参数%s", key)
+		}
+		if err = setParamField(f, values[key]); err != nil {
+			return fmt.Errorf("参数%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func decodeFlagParams(params string, v reflect.Value) error {
+	args, err := splitArgs(params)
+	if err != nil {
+		return err
+	}
+	fields, err := paramFields(v)
+	if err != nil {
+		return err
+	}
+	values := make(map[string][]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || strings.TrimLeft(arg, "-") == "" {
+			return fmt.Errorf("无法识别的参数%s", arg)
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		val, hasVal := "", false
+		if n := strings.Index(name, "="); n >= 0 {
+			name, val, hasVal = name[:n], name[n+1:], true
+		}
+		f, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("未知参数%s", name)
+		}
+		if !hasVal {
+			if f.Kind() == reflect.Bool {
+				val = "true"
+			} else if i+1 < len(args) {
+				i++
+				val = args[i]
+			} else {
+				return fmt.Errorf("参数%s缺少值", name)
+			}
+		}
+		values[name] = append(values[name], val)
+	}
+	for _, name := range sortedKeys(values) {
+		if err = setParamField(fields[strings.ToLower(name)], values[name]); err != nil {
+			return fmt.Errorf("参数%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// splitArgs 按空白拆分命令行参数，支持单引号和双引号
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var cur bytes.Buffer
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("引号未闭合")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// paramFields 参数名(小写)到字段的映射
+func paramFields(v reflect.Value) (map[string]reflect.Value, error) {
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("参数类型%s不是struct，只支持JSON格式", v.Type())
+	}
+	fields := make(map[string]reflect.Value)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("param"); ok {
+			name = tag
+		} else if tag, ok = sf.Tag.Lookup("json"); ok {
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		fields[strings.ToLower(name)] = v.Field(i)
+	}
+	return fields, nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setParamField 设置字段值，非切片字段出现多次时取最后一个
+func setParamField(f reflect.Value, values []string) error {
+	if f.Kind() == reflect.Slice && !reflect.PtrTo(f.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(f.Type(), 0, len(values))
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				e := reflect.New(f.Type().Elem()).Elem()
+				if err := setParamValue(e, item); err != nil {
+					return err
+				}
+				s = reflect.Append(s, e)
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+	return setParamValue(f, values[len(values)-1])
+}
+
+func setParamValue(f reflect.Value, value string) error {
+	if f.Kind() == reflect.Ptr {
+		e := reflect.New(f.Type().Elem())
+		if err := setParamValue(e.Elem(), value); err != nil {
+			return err
+		}
+		f.Set(e)
+		return nil
+	}
+	if reflect.PtrTo(f.Type()).Implements(textUnmarshalerType) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("不支持的字段类型%s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package xxl
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testParams struct {
+	Name    string        `json:"name"`
+	Count   int           `json:"count"`
+	Verbose bool          `json:"verbose"`
+	Tags    []string      `json:"tags"`
+	Wait    time.Duration `json:"wait"`
+	Limit   *int          `param:"max" json:"limit"`
+	Ratio   float64
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestDecodeParams(t *testing.T) {
+	defaults := testParams{Name: "default", Count: 1, Tags: []string{"a"}}
+	tests := []struct {
+		name    string
+		params  string
+		want    testParams
+		wantErr string
+	}{
+		{name: "empty", params: "  ", want: defaults},
+		{name: "json", params: `{"name":"x","count":3,"tags":["b","c"],"wait":1000000000}`,
+			want: testParams{Name: "x", Count: 3, Tags: []string{"b", "c"}, Wait: time.Second}},
+		{name: "json keeps defaults", params: `{"verbose":true}`,
+			want: testParams{Name: "default", Count: 1, Verbose: true, Tags: []string{"a"}}},
+		{name: "json unknown field", params: `{"unknown":1}`, wantErr: "json"},
+		{name: "json trailing data", params: `{"count":2} {}`, wantErr: "多余内容"},
+		{name: "query", params: "name=q&count=5&verbose=true&wait=1m30s&max=7&ratio=0.5",
+			want: testParams{Name: "q", Count: 5, Verbose: true, Tags: []string{"a"}, Wait: 90 * time.Second, Limit: intPtr(7), Ratio: 0.5}},
+		{name: "query case insensitive", params: "NAME=q&Count=2",
+			want: testParams{Name: "q", Count: 2, Tags: []string{"a"}}},
+		{name: "query repeated slice", params: "tags=x&tags=y",
+			want: testParams{Name: "default", Count: 1, Tags: []string{"x", "y"}}},
+		{name: "query comma slice", params: "tags=x,y,z",
+			want: testParams{Name: "default", Count: 1, Tags: []string{"x", "y", "z"}}},
+		{name: "query last value wins", params: "count=2&count=3",
+			want: testParams{Name: "default", Count: 3, Tags: []string{"a"}}},
+		{name: "query unknown", params: "foo=1", wantErr: "未知参数foo"},
+		{name: "query bad int", params: "count=abc", wantErr: "query"},
+		{name: "query bad duration", params: "wait=soon", wantErr: "参数wait"},
+		{name: "flag", params: "--name=f --count 4 -tags x -tags y,z --wait=2s",
+			want: testParams{Name: "f", Count: 4, Tags: []string{"x", "y", "z"}, Wait: 2 * time.Second}},
+		{name: "flag bool shorthand", params: "--verbose --name n",
+			want: testParams{Name: "n", Count: 1, Verbose: true, Tags: []string{"a"}}},
+		{name: "flag bool explicit", params: "--verbose=false",
+			want: testParams{Name: "default", Count: 1, Tags: []string{"a"}}},
+		{name: "flag double quotes", params: `--name "hello world" --count 2`,
+			want: testParams{Name: "hello world", Count: 2, Tags: []string{"a"}}},
+		{name: "flag single quotes", params: `--name='it is' --max 9`,
+			want: testParams{Name: "it is", Count: 1, Tags: []string{"a"}, Limit: intPtr(9)}},
+		{name: "flag unclosed quote", params: `--name "oops`, wantErr: "引号未闭合"},
+		{name: "flag missing value", params: "--count", wantErr: "缺少值"},
+		{name: "flag positional", params: "--count 1 extra", wantErr: "无法识别的参数extra"},
+		{name: "flag unknown", params: "--foo", wantErr: "未知参数foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeParams(tt.params, defaults)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				if !reflect.DeepEqual(got, defaults) {
+					t.Fatalf("got %+v on error, want defaults", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if !reflect.DeepEqual(defaults.Tags, []string{"a"}) {
+		t.Fatalf("defaults modified: %v", defaults.Tags)
+	}
+}
+
+func TestDecodeParamsPointer(t *testing.T) {
+	defaults := &testParams{Name: "default", Tags: []string{"a"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, params := range []string{`{"name":"json","tags":["b"]}`, "name=query&tags=c", "--name flag"} {
+				got, err := DecodeParams(params, defaults)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got == defaults {
+					t.Error("decoded into defaults")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if defaults.Name != "default" || !reflect.DeepEqual(defaults.Tags, []string{"a"}) {
+		t.Fatalf("defaults modified: %+v", defaults)
+	}
+
+	type sub struct {
+		A     int   `json:"a"`
+		Items []int `json:"items"`
+	}
+	type nested struct {
+		Limit *int           `json:"limit"`
+		Sub   *sub           `json:"sub"`
+		Subs  []sub          `json:"subs"`
+		Extra map[string]sub `json:"extra"`
+	}
+	nestedDefaults := nested{
+		Limit: intPtr(1),
+		Sub:   &sub{A: 1, Items: make([]int, 1, 4)},
+		Subs:  []sub{{A: 1, Items: make([]int, 1, 4)}},
+		Extra: map[string]sub{"k": {A: 1}},
+	}
+	gotNested, err := DecodeParams(`{"limit":5,"sub":{"a":9,"items":[7,8]},"subs":[{"a":9,"items":[7]}],"extra":{"k":{"a":9}}}`, nestedDefaults)
+	if err != nil || *gotNested.Limit != 5 || gotNested.Sub.A != 9 || gotNested.Subs[0].A != 9 || gotNested.Extra["k"].A != 9 {
+		t.Fatalf("nested: got %+v, err %v", gotNested, err)
+	}
+	if *nestedDefaults.Limit != 1 || nestedDefaults.Sub.A != 1 || nestedDefaults.Subs[0].A != 1 ||
+		nestedDefaults.Extra["k"].A != 1 || nestedDefaults.Sub.Items[:2][1] != 0 || nestedDefaults.Subs[0].Items[:2][1] != 0 {
+		t.Fatalf("nested defaults modified: %+v %+v %+v", nestedDefaults, *nestedDefaults.Sub, nestedDefaults.Subs)
+	}
+	if gotNested, err = DecodeParams("limit=6", nestedDefaults); err != nil || *gotNested.Limit != 6 || *nestedDefaults.Limit != 1 {
+		t.Fatalf("query pointer field: got %+v, err %v, defaults %d", gotNested, err, *nestedDefaults.Limit)
+	}
+
+	got, err := DecodeParams[*testParams]("count=2", nil)
+	if err != nil || got == nil || got.Count != 2 {
+		t.Fatalf("nil defaults: got %+v, err %v", got, err)
+	}
+}
+
+func TestDecodeParamsNonStruct(t *testing.T) {
+	defaults := map[string]int{"b": 2}
+	got, err := DecodeParams(`{"a":1}`, defaults)
+	if err != nil || !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2}) {
+		t.Fatalf("got %v, err %v", got, err)
+	}
+	if !reflect.DeepEqual(defaults, map[string]int{"b": 2}) {
+		t.Fatalf("defaults modified: %v", defaults)
+	}
+	if _, err = DecodeParams("a=1", map[string]int{}); err == nil || !strings.Contains(err.Error(), "只支持JSON格式") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "  -a   b\t--c=d\n", want: []string{"-a", "b", "--c=d"}},
+		{in: `--name "a b" 'c "d"'`, want: []string{"--name", "a b", `c "d"`}},
+		{in: `--empty ""`, want: []string{"--empty", ""}},
+		{in: `--x=a"b c"d`, want: []string{"--x=ab cd"}},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
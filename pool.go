@@ -0,0 +1,163 @@
+package xxl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+/**
+任务并发控制，MaxConcurrency限制执行器同时运行的任务数，WithMaxConcurrency限制单个任务的并发数，
+超过限制时按OverflowPolicy拒绝调度或排队等待
+*/
+
+// 并发已满时的处理策略
+const (
+	OverflowReject = "REJECT" //拒绝调度，回调失败
+	OverflowQueue  = "QUEUE"  //排队等待，等待数超过PoolQueueSize时拒绝
+
+	overflowWait = "WAIT" //已接受的调度(如串行队列中的下一个)，不限制等待数
+)
+
+// poolTicket 一次调度占用的并发，ready关闭后才能执行
+type poolTicket struct {
+	name    string
+	limit   int
+	ready   chan struct{}
+	granted bool
+}
+
+// pool 执行器并发控制
+type pool struct {
+	mu        sync.Mutex
+	max       int            //执行器最大并发，小于等于0不限制
+	queueSize int            //最大等待数，小于等于0不限制
+	running   int            //运行中的任务数
+	handlers  map[string]int //[handler]运行中的任务数
+	waiters   []*poolTicket  //按调度顺序等待的任务
+}
+
+func newPool(max, queueSize int) *pool {
+	return &pool{
+		max:       max,
+		queueSize: queueSize,
+		handlers:  make(map[string]int),
+	}
+}
+
+// free 是否可以立即运行，调用方持有锁
+func (p *pool) free(name string, limit int) bool {
+	if p.max > 0 && p.running >= p.max {
+		return false
+	}
+	return limit <= 0 || p.handlers[name] < limit
+}
+
+func (p *pool) grant(t *poolTicket) {
+	p.running++
+	p.handlers[t.name]++
+	t.granted = true
+	close(t.ready)
+}
+
+// reserve 申请并发，limit为任务的并发限制，并发已满时按policy拒绝或排队
+func (p *pool) reserve(name string, limit int, policy string) (*poolTicket, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := &poolTicket{name: name, limit: limit, ready: make(chan struct{})}
+	if p.free(name, limit) {
+		p.grant(t)
+		return t, nil
+	}
+	switch policy {
+	case OverflowQueue:
+		if p.queueSize > 0 && len(p.waiters) >= p.queueSize {
+			return nil, errors.New("The executor wait queue is full")
+		}
+	case overflowWait:
+	default:
+		if p.max > 0 && p.running >= p.max {
+			return nil, fmt.Errorf("The executor is busy, max concurrency %d reached", p.max)
+		}
+		return nil, fmt.Errorf("The job handler is busy, max concurrency %d reached", limit)
+	}
+	p.waiters = append(p.waiters, t)
+	return t, nil
+}
+
+// wait 等待轮到执行，cxt结束时放弃等待并返回false
+func (p *pool) wait(cxt context.Context, t *poolTicket) bool {
+	select {
+	case <-t.ready:
+		return true
+	case <-cxt.Done():
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t.granted { //放弃等待的同时刚好轮到
+		p.releaseLocked(t)
+		return false
+	}
+	for i, w := range p.waiters {
+		if w == t {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			break
+		}
+	}
+	p.schedule()
+	return false
+}
+
+// release 释放并发，唤醒等待的任务
+func (p *pool) release(t *poolTicket) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.releaseLocked(t)
+}
+
+func (p *pool) releaseLocked(t *poolTicket) {
+	p.running--
+	if p.handlers[t.name]--; p.handlers[t.name] <= 0 {
+		delete(p.handlers, t.name)
+	}
+	p.schedule()
+}
+
+// schedule 按顺序唤醒可以运行的任务，任务并发已满的跳过，不影响其他任务
+func (p *pool) schedule() {
+	i := 0
+	for _, t := range p.waiters {
+		if p.free(t.name, t.limit) {
+			p.grant(t)
+			continue
+		}
+		p.waiters[i] = t
+		i++
+	}
+	for j := i; j < len(p.waiters); j++ {
+		p.waiters[j] = nil
+	}
+	p.waiters = p.waiters[:i]
+}
+
+// waiting 是否在等待
+func (p *pool) waiting(t *poolTicket) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !t.granted
+}
+
+// saturated 执行器并发是否已满
+func (p *pool) saturated() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.max > 0 && p.running >= p.max
+}
+
+// stats 运行中和等待中的任务数
+func (p *pool) stats() (running, waiting int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running, len(p.waiters)
+}
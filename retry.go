@@ -0,0 +1,77 @@
+package xxl
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+/**
+执行器本地重试，任务返回错误时在同一次调度内重试，日志ID不变，只回调一次最终结果。
+调度中心的失败重试次数会重新调度整个任务，适合执行器宕机等情况
+*/
+
+// RetryPolicy 任务重试策略
+type RetryPolicy struct {
+	MaxAttempts int                  //最多执行次数，包括第一次，小于等于1不重试
+	Backoff     time.Duration        //第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff  time.Duration        //最长等待时间，0不限制
+	Jitter      float64              //等待时间的随机浮动比例，0~1
+	Retryable   func(err error) bool //是否重试该错误，为空时panic以外的错误都重试
+}
+
+// WithRetry 设置任务重试策略，超时和被终止的任务不重试
+func WithRetry(policy RetryPolicy) TaskOption {
+	return func(h *jobHandler) {
+		h.retry = &policy
+	}
+}
+
+// retryable 第attempt次执行失败后是否重试
+func (p *RetryPolicy) retryable(attempt int, err error) bool {
+	if p == nil || err == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	var panicErr *PanicError
+	return !errors.As(err, &panicErr)
+}
+
+// delay 第attempt次执行失败后的等待时间
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	//翻倍到超过math.MaxInt64/2后不再翻倍，避免溢出
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		f := float64(d) + (rand.Float64()*2-1)*p.Jitter*float64(d)
+		if f >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		d = time.Duration(f)
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// sleep 等待d，cxt结束时返回false
+func sleep(cxt context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-cxt.Done():
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+package xxl
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+/**
+分片广播，BroadcastIndex为当前分片序号(从0开始)，BroadcastTotal为分片总数
+*/
+
+// Shard 分片参数
+type Shard struct {
+	Index int64 // 当前分片序号，从0开始
+	Total int64 // 分片总数，小于1时视为不分片
+}
+
+// NewShard 创建分片参数，总数小于1时视为不分片
+func NewShard(index, total int64) Shard {
+	if total < 1 {
+		return Shard{Index: 0, Total: 1}
+	}
+	return Shard{Index: index, Total: total}
+}
+
+// normalize 总数小于1时与NewShard一样视为不分片，避免零值Shard{}除零
+func (s Shard) normalize() Shard {
+	return NewShard(s.Index, s.Total)
+}
+
+type runReqKey struct{}
+
+func withRunReq(cxt context.Context, param *RunReq) context.Context {
+	return context.WithValue(cxt, runReqKey{}, param)
+}
+
+// ShardFromContext 从TaskFunc的context中获取分片参数，非分片广播调度时为{0,1}
+func ShardFromContext(cxt context.Context) Shard {
+	if param, ok := cxt.Value(runReqKey{}).(*RunReq); ok && param != nil {
+		return NewShard(param.BroadcastIndex, param.BroadcastTotal)
+	}
+	return NewShard(0, 1)
+}
+
+// OwnsInt 整数key是否属于当前分片，按key取模
+func (s Shard) OwnsInt(key int64) bool {
+	s = s.normalize()
+	mod := key % s.Total
+	if mod < 0 {
+		mod += s.Total
+	}
+	return mod == s.Index
+}
+
+// OwnsString 字符串key是否属于当前分片，使用一致性哈希，分片总数变化时只有少量key迁移
+func (s Shard) OwnsString(key string) bool {
+	s = s.normalize()
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return jumpHash(h.Sum64(), s.Total) == s.Index
+}
+
+// Range 将[lo,hi)均分给所有分片，返回当前分片负责的[start,end)，余数分给前面的分片
+func (s Shard) Range(lo, hi int64) (start, end int64) {
+	s = s.normalize()
+	if hi <= lo || s.Index < 0 || s.Index >= s.Total {
+		return lo, lo
+	}
+	size := hi - lo
+	q, r := size/s.Total, size%s.Total
+	start = lo + s.Index*q + min64(s.Index, r)
+	end = start + q
+	if s.Index < r {
+		end++
+	}
+	return start, end
+}
+
+// jumpHash Jump Consistent Hash，返回[0,buckets)
+func jumpHash(key uint64, buckets int64) int64 {
+	var b, j int64 = -1, 0
+	for j < buckets {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return b
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ShardResult 模拟分片调度的执行结果
+type ShardResult struct {
+	Shard Shard
+	Msg   string
+	Err   error
+}
+
+// SimulateBroadcast 在本地依次模拟total个分片广播调度，用于单元测试任务的分片逻辑
+func SimulateBroadcast(cxt context.Context, total int64, param RunReq, job JobFunc) []ShardResult {
+	if total < 1 {
+		total = 1
+	}
+	results := make([]ShardResult, 0, total)
+	for i := int64(0); i < total; i++ {
+		p := param
+		p.BroadcastIndex, p.BroadcastTotal = i, total
+		msg, err := job(withRunReq(cxt, &p), &p)
+		results = append(results, ShardResult{Shard: NewShard(i, total), Msg: msg, Err: err})
+	}
+	return results
+}
@@ -0,0 +1,70 @@
+package xxl
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+/**
+只读状态查询，不经过调度中心即可查看本机注册和运行中的任务，
+返回内容包含任务参数，设置AccessToken后与调度中心的请求一样校验令牌
+*/
+
+// 注册的任务列表
+func (e *executor) statusTasks(writer http.ResponseWriter, request *http.Request) {
+	if !e.checkToken(writer, request) {
+		return
+	}
+	handlers := e.regList.GetAll()
+	list := make([]*TaskStatus, 0, len(handlers))
+	for _, h := range handlers {
+		status := &TaskStatus{Pattern: h.name}
+		if h.spec != nil {
+			status.Description = h.spec.Desc
+			status.ScheduleType = h.spec.ScheduleType
+			status.Cron = h.spec.ScheduleConf
+		}
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Pattern < list[j].Pattern
+	})
+	writeJSON(writer, list)
+}
+
+// 运行中的任务列表
+func (e *executor) statusRunning(writer http.ResponseWriter, request *http.Request) {
+	if !e.checkToken(writer, request) {
+		return
+	}
+	tasks := e.runList.GetAll()
+	now := time.Now()
+	list := make([]*RunningStatus, 0, len(tasks))
+	for _, t := range tasks {
+		start := time.UnixMilli(t.StartTime)
+		list = append(list, &RunningStatus{
+			JobID:          t.Id,
+			LogID:          t.Param.LogID,
+			Handler:        t.Name,
+			Params:         t.Param.ExecutorParams,
+			StartTime:      start.Format("2006-01-02 15:04:05"),
+			ElapsedMs:      now.Sub(start).Milliseconds(),
+			BroadcastIndex: t.Param.BroadcastIndex,
+			BroadcastTotal: t.Param.BroadcastTotal,
+			Queued:         e.queue.Len(Int64ToStr(t.Id)),
+			Waiting:        e.pool.waiting(t.ticket),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].JobID < list[j].JobID
+	})
+	writeJSON(writer, list)
+}
+
+func writeJSON(writer http.ResponseWriter, v interface{}) {
+	writer.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	str, _ := json.Marshal(v)
+	_, _ = writer.Write(str)
+}
@@ -2,40 +2,193 @@ package xxl
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"runtime/debug"
+	"sync"
+	"time"
 )
 
 // TaskFunc 任务执行函数
 type TaskFunc func(cxt context.Context, param *RunReq) string
 
-// Task 任务
+// JobFunc 任务执行函数，返回错误时回调失败，context超时回调超时
+type JobFunc func(cxt context.Context, param *RunReq) (string, error)
+
+// Job 将TaskFunc适配为JobFunc，正常返回即为成功
+func (f TaskFunc) Job() JobFunc {
+	return func(cxt context.Context, param *RunReq) (string, error) {
+		return f(cxt, param), nil
+	}
+}
+
+// JobError 指定回调结果码的错误
+type JobError struct {
+	Code int64
+	Msg  string
+}
+
+// NewJobError 创建指定结果码的错误
+func NewJobError(code int64, msg string) *JobError {
+	return &JobError{Code: code, Msg: msg}
+}
+
+func (e *JobError) Error() string {
+	return e.Msg
+}
+
+// jobHandler 注册的任务定义，注册后不再修改，每次调度由它创建新的Task
+type jobHandler struct {
+	name           string
+	spec           *JobSpec //调度中心任务定义，为空时只在本地注册
+	fn             JobFunc
+	middlewares    []Middleware
+	maxConcurrency int          //同时运行的最大数量，小于等于0不限制
+	retry          *RetryPolicy //重试策略，为空时不重试
+}
+
+// TaskOption 任务注册选项
+type TaskOption func(h *jobHandler)
+
+// WithMiddleware 设置任务中间件，在执行器全局中间件之后执行
+func WithMiddleware(mws ...Middleware) TaskOption {
+	return func(h *jobHandler) {
+		h.middlewares = append(h.middlewares, mws...)
+	}
+}
+
+// WithMaxConcurrency 设置任务同时运行的最大数量，超过时按执行器的OverflowPolicy处理
+func WithMaxConcurrency(n int) TaskOption {
+	return func(h *jobHandler) {
+		h.maxConcurrency = n
+	}
+}
+
+// newTask 创建一次调度的任务实例，mws为执行器的中间件
+func (h *jobHandler) newTask(param *RunReq, mws []Middleware) *Task {
+	chain := make([]Middleware, 0, len(mws)+len(h.middlewares))
+	chain = append(chain, mws...)
+	chain = append(chain, h.middlewares...)
+	return &Task{
+		Id:    param.JobID,
+		Name:  h.name,
+		Param: param,
+		fn:    chainMiddleware(h.fn, chain...),
+		limit: h.maxConcurrency,
+		retry: h.retry,
+	}
+}
+
+// Task 一次调度的任务实例
 type Task struct {
 	Id        int64
 	Name      string
 	Ext       context.Context
 	Param     *RunReq
-	fn        TaskFunc
+	fn        JobFunc
 	Cancel    context.CancelFunc
-	StartTime int64
-	EndTime   int64
+	StartTime int64 //开始时间(毫秒)
+	EndTime   int64 //结束时间(毫秒)
+	Code      int64 //执行结果
+	Msg       string
 	//日志
-	log Logger
+	log StructuredLogger
+	//执行日志文件
+	logFile *jobLogFile
+	//结果只回调一次
+	once sync.Once
+	//被终止的原因
+	mu      sync.Mutex
+	killMsg string
+	//执行结果分类，用于监控统计
+	outcome string
+	//同时运行的最大数量和占用的并发
+	limit  int
+	ticket *poolTicket
+	//重试策略
+	retry *RetryPolicy
 }
 
 // Run 运行任务
 func (t *Task) Run(callback func(code int64, msg string)) {
-	defer func(cancel func()) {
-		if err := recover(); err != nil {
-			t.log.Info(t.Info()+" panic: %v", err)
-			debug.PrintStack() //堆栈跟踪
-			callback(FailureCode, fmt.Sprintf("task panic:%v", err))
-			cancel()
+	if t.StartTime == 0 {
+		t.StartTime = time.Now().UnixMilli()
+	}
+	msg, err := t.fn(t.Ext, t.Param)
+	attempt := 1
+	for ; t.retry.retryable(attempt, err) && t.Ext.Err() == nil; attempt++ {
+		d := t.retry.delay(attempt)
+		GetJobLogger(t.Ext).Warn("第%d次执行失败：%v，%s后重试", attempt, err, d.Round(time.Millisecond))
+		if t.log != nil {
+			t.log.Warn("任务执行失败，等待重试", append(jobFields(t.Param), "attempt", attempt, "err", err, "delay", d)...)
+		}
+		if !sleep(t.Ext, d) {
+			break
 		}
-	}(t.Cancel)
-	msg := t.fn(t.Ext, t.Param)
-	callback(SuccessCode, msg)
-	return
+		msg, err = t.fn(t.Ext, t.Param)
+	}
+	code, msg, outcome := t.result(msg, err)
+	if attempt > 1 {
+		GetJobLogger(t.Ext).Info("共执行%d次", attempt)
+		msg = fmt.Sprintf("%s (attempts: %d)", msg, attempt)
+	}
+	t.finish(code, msg, outcome, callback)
+}
+
+// 任务执行结果分类
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+	outcomePanic   = "panic"
+	outcomeTimeout = "timeout"
+	outcomeKilled  = "killed"
+)
+
+// result 根据返回值和context状态得到回调结果，超时和被终止优先于返回值
+func (t *Task) result(msg string, err error) (int64, string, string) {
+	switch t.Ext.Err() {
+	case context.DeadlineExceeded:
+		return TimeoutCode, "job execute timeout", outcomeTimeout
+	case context.Canceled:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.killMsg == "" {
+			return FailureCode, "job killed", outcomeKilled
+		}
+		return FailureCode, t.killMsg, outcomeKilled
+	}
+	if err != nil {
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			return FailureCode, err.Error(), outcomePanic
+		}
+		var jobErr *JobError
+		if errors.As(err, &jobErr) {
+			return jobErr.Code, jobErr.Msg, outcomeFailure
+		}
+		return FailureCode, err.Error(), outcomeFailure
+	}
+	return SuccessCode, msg, outcomeSuccess
+}
+
+// kill 终止任务，msg为回调的失败信息
+func (t *Task) kill(msg string) {
+	t.mu.Lock()
+	if t.killMsg == "" {
+		t.killMsg = msg
+	}
+	t.mu.Unlock()
+	t.Cancel()
+}
+
+// finish 结束任务并取消context，结果只回调一次
+func (t *Task) finish(code int64, msg, outcome string, callback func(code int64, msg string)) {
+	t.once.Do(func() {
+		t.EndTime = time.Now().UnixMilli()
+		t.Code, t.Msg = code, msg
+		t.outcome = outcome
+		t.Cancel()
+		callback(code, msg)
+	})
 }
 
 // Info 任务信息
@@ -22,11 +22,15 @@ func (t *taskList) Get(key string) *Task {
 	return t.data[key]
 }
 
-// GetAll 获取数据
+// GetAll 获取数据副本
 func (t *taskList) GetAll() map[string]*Task {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.data
+	data := make(map[string]*Task, len(t.data))
+	for k, v := range t.data {
+		data[k] = v
+	}
+	return data
 }
 
 // Del 设置数据
@@ -38,6 +42,8 @@ func (t *taskList) Del(key string) {
 
 // Len 长度
 func (t *taskList) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return len(t.data)
 }
 
@@ -48,3 +54,53 @@ func (t *taskList) Exists(key string) bool {
 	_, ok := t.data[key]
 	return ok
 }
+
+// CompareAndDel 仅当key对应的仍是val时删除，避免删除覆盖后的新任务
+func (t *taskList) CompareAndDel(key string, val *Task) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.data[key] != val {
+		return false
+	}
+	delete(t.data, key)
+	return true
+}
+
+//注册任务列表 [ExecutorHandler]任务定义
+type handlerList struct {
+	mu   sync.RWMutex
+	data map[string]*jobHandler
+}
+
+// Set 设置数据
+func (h *handlerList) Set(key string, val *jobHandler) {
+	h.mu.Lock()
+	h.data[key] = val
+	h.mu.Unlock()
+}
+
+// Get 获取数据
+func (h *handlerList) Get(key string) *jobHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.data[key]
+}
+
+// Exists Key是否存在
+func (h *handlerList) Exists(key string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.data[key]
+	return ok
+}
+
+// GetAll 获取数据副本
+func (h *handlerList) GetAll() map[string]*jobHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	data := make(map[string]*jobHandler, len(h.data))
+	for k, v := range h.data {
+		data[k] = v
+	}
+	return data
+}
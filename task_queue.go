@@ -0,0 +1,77 @@
+package xxl
+
+import "sync"
+
+// 单机串行队列 [JobID]等待执行的任务，按调度顺序执行
+type taskQueue struct {
+	mu   sync.Mutex
+	data map[string][]*Task
+}
+
+// Push 加入队尾，max大于0时超过队列深度返回false
+func (q *taskQueue) Push(key string, val *Task, max int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if max > 0 && len(q.data[key]) >= max {
+		return false
+	}
+	q.data[key] = append(q.data[key], val)
+	return true
+}
+
+// Pop 取出队首
+func (q *taskQueue) Pop(key string) *Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := q.data[key]
+	if len(list) == 0 {
+		return nil
+	}
+	val := list[0]
+	list[0] = nil
+	if len(list) == 1 {
+		delete(q.data, key)
+	} else {
+		q.data[key] = list[1:]
+	}
+	return val
+}
+
+// Clear 清空队列，返回被丢弃的任务
+func (q *taskQueue) Clear(key string) []*Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := q.data[key]
+	delete(q.data, key)
+	return list
+}
+
+// Len 队列长度
+func (q *taskQueue) Len(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.data[key])
+}
+
+// ClearAll 清空所有队列，返回被丢弃的任务
+func (q *taskQueue) ClearAll() []*Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var list []*Task
+	for _, v := range q.data {
+		list = append(list, v...)
+	}
+	q.data = make(map[string][]*Task)
+	return list
+}
+
+// Size 所有队列中等待的任务总数
+func (q *taskQueue) Size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	size := 0
+	for _, v := range q.data {
+		size += len(v)
+	}
+	return size
+}
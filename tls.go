@@ -0,0 +1,61 @@
+package xxl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+/**
+执行器HTTPS服务，设置证书后监听TLS，注册地址自动使用https，
+设置客户端CA后要求调度中心提供该CA签发的客户端证书(双向TLS)
+*/
+
+// tlsEnabled 是否使用HTTPS
+func (o *Options) tlsEnabled() bool {
+	return o.tlsConfig != nil || o.TLSCertFile != ""
+}
+
+// scheme 注册地址的协议
+func (e *executor) scheme() string {
+	if e.opts.tlsEnabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// serverTLSConfig 服务端TLS配置，未启用HTTPS时返回nil
+func (e *executor) serverTLSConfig() (*tls.Config, error) {
+	if !e.opts.tlsEnabled() {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if e.opts.tlsConfig != nil {
+		cfg = e.opts.tlsConfig.Clone()
+	}
+	if e.opts.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(e.opts.TLSCertFile, e.opts.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载TLS证书失败: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	if e.opts.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(e.opts.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端CA失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("客户端CA[%s]中没有有效的证书", e.opts.TLSClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return nil, errors.New("未设置TLS证书")
+	}
+	return cfg, nil
+}
@@ -12,22 +12,25 @@ func Int64ToStr(i int64) string {
 
 //执行任务回调
 func returnCall(req *RunReq, code int64, msg string) []byte {
-	data := call{
-		&callElement{
-			LogID:      req.LogID,
-			LogDateTim: req.LogDateTime,
-			ExecuteResult: &ExecuteResult{
-				Code: code,
-				Msg:  msg,
-			},
-			HandleCode: int(code),
-			HandleMsg:  msg,
-		},
-	}
+	data := call{newCallElement(req, code, msg)}
 	str, _ := json.Marshal(data)
 	return str
 }
 
+//任务回调结果
+func newCallElement(req *RunReq, code int64, msg string) *callElement {
+	return &callElement{
+		LogID:      req.LogID,
+		LogDateTim: req.LogDateTime,
+		ExecuteResult: &ExecuteResult{
+			Code: code,
+			Msg:  msg,
+		},
+		HandleCode: int(code),
+		HandleMsg:  msg,
+	}
+}
+
 //杀死任务返回
 func returnKill(req *killReq, code int64) []byte {
 	msg := ""
@@ -56,6 +59,36 @@ func returnIdleBeat(code int64) []byte {
 	return str
 }
 
+//忙碌返回，msg为忙碌原因
+func returnBusy(msg string) []byte {
+	data := res{
+		Code: FailureCode,
+		Msg:  msg,
+	}
+	str, _ := json.Marshal(data)
+	return str
+}
+
+//调度失败返回，/run同步拒绝调度时使用，调度中心按ReturnT解析
+func returnFail(msg string) []byte {
+	data := res{
+		Code: FailureCode,
+		Msg:  msg,
+	}
+	str, _ := json.Marshal(data)
+	return str
+}
+
+//请求令牌错误返回
+func returnTokenErr() []byte {
+	data := &res{
+		Code: FailureCode,
+		Msg:  "The access token is wrong.",
+	}
+	str, _ := json.Marshal(data)
+	return str
+}
+
 //通用返回
 func returnGeneral() []byte {
 	data := &res{
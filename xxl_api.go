@@ -1,25 +1,60 @@
 package xxl
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-var client *http.Client
+// newAdminHTTPClient 后台接口的http客户端，超时时间取Options.Timeout
+func newAdminHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: adminTimeout(timeout),
+		// 登录会话失效时调度中心重定向到登录页，不跟随重定向，以便重新登录
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// ErrSessionExpired 登录会话失效，重新登录后仍失效时返回
+var ErrSessionExpired = errors.New("登录会话失效")
 
-func init() {
-	client = &http.Client{}
+// AdminError 调用调度中心后台接口错误
+type AdminError struct {
+	Op   string // 操作名称
+	Code int    // 调度中心返回码或HTTP状态码，请求失败时为0
+	Msg  string // 调度中心返回的错误信息
+	Err  error  // 底层错误
 }
 
+func (e *AdminError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("调用接口【%s】错误: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("调用接口【%s】错误: code=%d, msg=%s", e.Op, e.Code, e.Msg)
+}
+
+func (e *AdminError) Unwrap() error {
+	return e.Err
+}
+
+// xxlApi 调度中心后台接口，用于同步执行器和任务，需要超管账号
 type xxlApi struct {
 	Options
-	log    logger
-	cookie string
+	log     StructuredLogger
+	client  *http.Client
+	admin   *adminClient      //调度中心地址选择，与注册、回调共用健康状态
+	mu      sync.Mutex        //同一时间只有一个请求使用和刷新登录会话
+	cookies map[string]string //[调度中心地址]登录会话cookie
 }
 
 type xxlExecutor struct {
@@ -33,12 +68,24 @@ type xxlExecutor struct {
 }
 
 type xxlJob struct {
-	JobGroup        int    `json:"jobGroup,omitempty"`
-	ScheduleConf    string `json:"scheduleConf,omitempty"`
-	ExecutorHandler string `json:"executorHandler,omitempty"`
-	JobDesc         string `json:"jobDesc,omitempty"`
-	Id              int    `json:"id,omitempty"`
-	Author          string `json:"author,omitempty"`
+	Id                     int    `json:"id,omitempty"`
+	JobGroup               int    `json:"jobGroup,omitempty"`
+	JobDesc                string `json:"jobDesc,omitempty"`
+	Author                 string `json:"author,omitempty"`
+	AlarmEmail             string `json:"alarmEmail,omitempty"`
+	ScheduleType           string `json:"scheduleType,omitempty"`
+	ScheduleConf           string `json:"scheduleConf,omitempty"`
+	MisfireStrategy        string `json:"misfireStrategy,omitempty"`
+	ExecutorRouteStrategy  string `json:"executorRouteStrategy,omitempty"`
+	ExecutorHandler        string `json:"executorHandler,omitempty"`
+	ExecutorParam          string `json:"executorParam,omitempty"`
+	ExecutorBlockStrategy  string `json:"executorBlockStrategy,omitempty"`
+	ExecutorTimeout        int    `json:"executorTimeout,omitempty"`
+	ExecutorFailRetryCount int    `json:"executorFailRetryCount,omitempty"`
+	GlueType               string `json:"glueType,omitempty"`
+	GlueRemark             string `json:"glueRemark,omitempty"`
+	ChildJobId             string `json:"childJobId,omitempty"`
+	TriggerStatus          int    `json:"triggerStatus,omitempty"`
 }
 
 type webResExecutor struct {
@@ -59,364 +106,326 @@ type wenResCode struct {
 	Content string `json:"content,omitempty"`
 }
 
-func newXxlApi(opt Options) *xxlApi {
-	xxl := &xxlApi{Options: opt}
+func newXxlApi(opt Options, log StructuredLogger, admin *adminClient) *xxlApi {
+	xxl := &xxlApi{
+		Options: opt,
+		log:     log,
+		client:  newAdminHTTPClient(opt.Timeout),
+		admin:   admin,
+		cookies: make(map[string]string),
+	}
 	return xxl
 }
 
-func (x *xxlApi) login() error {
-	sendURL := fmt.Sprintf("%s/login", x.Options.ServerAddr)
-	header := make(map[string]string)
-	header["Content-Type"] = "application/x-www-form-urlencoded"
+// nodeDown 调度中心地址不可用(网络错误或5xx)，可以切换到下一个地址
+func nodeDown(err error) bool {
+	var ae *AdminError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	var ue *url.Error
+	return ae.Code >= http.StatusInternalServerError || errors.As(ae.Err, &ue)
+}
+
+// 登录，保存该地址的登录会话cookie
+func (x *xxlApi) login(addr string) error {
+	// https://apaas5.wodcloud.com/xxl-job-admin/login
+	const op = "登录"
 	body := url.Values{}
-	body.Add("userName", "admin")
+	body.Add("userName", x.Options.AdminUser)
 	body.Add("password", x.Options.AdminPwd)
-	resp, err := http.Post(sendURL, "application/x-www-form-urlencoded", strings.NewReader(body.Encode()))
+	resp, err := x.client.Post(addr+"/login", "application/x-www-form-urlencoded", strings.NewReader(body.Encode()))
 	if err != nil {
-		return err
-	} else {
-		cookie := resp.Header.Get("Set-Cookie")
-		x.cookie = cookie
+		return &AdminError{Op: op, Err: err}
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &AdminError{Op: op, Err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &AdminError{Op: op, Code: resp.StatusCode, Msg: http.StatusText(resp.StatusCode)}
+	}
+	res := wenResCode{}
+	if err = json.Unmarshal(respBody, &res); err != nil {
+		return &AdminError{Op: op, Err: err}
+	}
+	if res.Code != SuccessCode {
+		return &AdminError{Op: op, Code: res.Code, Msg: res.Msg}
+	}
+	cookies := make([]string, 0, 1)
+	for _, c := range resp.Cookies() {
+		cookies = append(cookies, c.Name+"="+c.Value)
+	}
+	if len(cookies) == 0 {
+		return &AdminError{Op: op, Code: res.Code, Msg: "没有返回登录会话"}
+	}
+	x.cookies[addr] = strings.Join(cookies, "; ")
+	return nil
+}
+
+// post 调用后台接口并解析返回的JSON，地址不可用时切换到下一个地址，
+// 每个地址单独登录，未登录或会话失效时登录后重试一次
+func (x *xxlApi) post(op, path string, body url.Values, out interface{}) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	nodes := x.admin.candidates()
+	if len(nodes) == 0 {
+		return &AdminError{Op: op, Err: errors.New("xxl-job admin address is empty")}
+	}
+	var err error
+	for _, node := range nodes {
+		var respBody []byte
+		respBody, err = x.postNode(op, node.addr, path, body)
+		if nodeDown(err) {
+			x.admin.failure(node)
+			x.log.Warn("调度中心后台不可用，切换地址", "op", op, "addr", node.addr, "err", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		x.admin.success(node)
+		if err = json.Unmarshal(respBody, out); err != nil {
+			return &AdminError{Op: op, Err: err}
+		}
 		return nil
 	}
+	return err
+}
+
+// postNode 向一个调度中心地址发送请求
+func (x *xxlApi) postNode(op, addr, path string, body url.Values) ([]byte, error) {
+	for relogin := false; ; relogin = true {
+		if x.cookies[addr] == "" {
+			if err := x.login(addr); err != nil {
+				return nil, err
+			}
+		}
+		respBody, err := x.send(op, addr, path, body)
+		if errors.Is(err, ErrSessionExpired) && !relogin {
+			x.log.Debug("登录会话失效，重新登录", "op", op, "addr", addr)
+			delete(x.cookies, addr)
+			continue
+		}
+		return respBody, err
+	}
+}
+
+// send 发送请求，重定向到登录页、401或返回登录页时为会话失效
+func (x *xxlApi) send(op, addr, path string, body url.Values) ([]byte, error) {
+	request, err := http.NewRequest("POST", addr+path, strings.NewReader(body.Encode()))
+	if err != nil {
+		return nil, &AdminError{Op: op, Err: err}
+	}
+	request.Header.Set("cookie", x.cookies[addr])
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := x.client.Do(request)
+	if err != nil {
+		return nil, &AdminError{Op: op, Err: err}
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &AdminError{Op: op, Err: err}
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized,
+		resp.StatusCode >= 300 && resp.StatusCode < 400 && strings.Contains(strings.ToLower(resp.Header.Get("Location")), "login"):
+		return nil, &AdminError{Op: op, Code: resp.StatusCode, Err: ErrSessionExpired}
+	case resp.StatusCode != http.StatusOK:
+		return nil, &AdminError{Op: op, Code: resp.StatusCode, Msg: http.StatusText(resp.StatusCode)}
+	case bytes.HasPrefix(bytes.TrimSpace(respBody), []byte("<")):
+		return nil, &AdminError{Op: op, Code: resp.StatusCode, Err: ErrSessionExpired}
+	}
+	return respBody, nil
+}
+
+// call 调用返回{"code":200,"msg":null,"content":null}格式的接口，返回content
+func (x *xxlApi) call(op, path string, body url.Values) (string, error) {
+	res := wenResCode{}
+	if err := x.post(op, path, body, &res); err != nil {
+		return "", err
+	}
+	if res.Code != SuccessCode {
+		return "", &AdminError{Op: op, Code: res.Code, Msg: res.Msg}
+	}
+	return res.Content, nil
 }
 
 // 检查并添加执行器
-func (x *xxlApi) checkOrAddExecutor(appname, alias, addressList string) {
+func (x *xxlApi) checkOrAddExecutor(appname, alias, addressList string) error {
 	executor, err := x.getExecutor(appname)
 	if err != nil {
-		x.log.Error("获取执行器错误:%s", err.Error())
+		return err
 	} else if executor.Appname == "" {
-		x.addExecutor(appname, alias, addressList)
-	} else if executor.Appname != "" && (executor.AddressList != addressList || executor.Title != alias) {
-		x.updateExecutor(appname, alias, addressList, executor.Id)
+		return x.addExecutor(appname, alias, addressList)
+	} else if executor.AddressList != addressList || executor.Title != alias {
+		return x.updateExecutor(appname, alias, addressList, executor.Id)
 	}
+	return nil
 }
 
-// 获取执行器
+// 获取执行器，不存在时返回空
 func (x *xxlApi) getExecutor(appname string) (executor xxlExecutor, err error) {
 	// https://apaas5.wodcloud.com/xxl-job-admin/jobgroup/pageList
-	if x.cookie == "" {
-		if err := x.login(); err != nil {
-			return executor, err
-		}
-	}
-	sendURL := fmt.Sprintf("%s/jobgroup/pageList", x.Options.ServerAddr)
 	body := url.Values{}
 	body.Add("appname", appname)
-	request, _ := http.NewRequest("POST", sendURL, strings.NewReader(body.Encode()))
-	request.Header.Set("cookie", x.cookie)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
-	if err != nil {
+	res := webResExecutor{}
+	if err = x.post("查询执行器", "/jobgroup/pageList", body, &res); err != nil {
 		return executor, err
-	} else {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return executor, err
-		}
-		res := webResExecutor{}
-		json.Unmarshal(respBody, &res)
-		for _, v := range res.Data {
-			if v.Appname == appname {
-				return v, nil
-			}
+	}
+	for _, v := range res.Data {
+		if v.Appname == appname {
+			return v, nil
 		}
-		return executor, err
 	}
+	return executor, nil
+}
+
+// 获取当前执行器Id
+func (x *xxlApi) executorId() (int, error) {
+	executor, err := x.getExecutor(x.RegistryKey)
+	if err != nil {
+		return 0, err
+	} else if executor.Id == 0 {
+		return 0, &AdminError{Op: "查询执行器", Msg: fmt.Sprintf("执行器[%s]不存在", x.RegistryKey)}
+	}
+	return executor.Id, nil
 }
 
 // 添加执行器
-func (x *xxlApi) addExecutor(appname, alias, addressList string) {
+func (x *xxlApi) addExecutor(appname, alias, addressList string) error {
 	// https://apaas5.wodcloud.com/xxl-job-admin/jobgroup/save
-	if x.cookie == "" {
-		if err := x.login(); err != nil {
-			return
-		}
-	}
-	sendURL := fmt.Sprintf("%s/jobgroup/save", x.Options.ServerAddr)
 	body := url.Values{}
 	body.Add("appname", appname)
 	body.Add("title", alias)
 	body.Add("addressType", "1")
 	body.Add("addressList", addressList)
-	request, _ := http.NewRequest("POST", sendURL, strings.NewReader(body.Encode()))
-	request.Header.Set("cookie", x.cookie)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
-	if err != nil {
-		x.log.Error("调用接口【新增执行器】错误：%s", err.Error())
-		return
-	} else {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		// {"code":200,"msg":null,"content":null}
-		if err != nil {
-			x.log.Error("调用接口【新增执行器】返回错误：%s", err.Error())
-			return
-		}
-		res := wenResCode{}
-		json.Unmarshal(respBody, &res)
-		if res.Code == 200 {
-			return
-		}
-		x.log.Error("调用接口【新增执行器】错误信息：%s", res.Msg)
-		return
-	}
+	_, err := x.call("新增执行器", "/jobgroup/save", body)
+	return err
 }
 
 // 更新执行器
-func (x *xxlApi) updateExecutor(appname, alias, addressList string, id int) {
-	// https://apaas5.wodcloud.com/xxl-job-admin/jobgroup/save
-	if x.cookie == "" {
-		if err := x.login(); err != nil {
-			return
-		}
-	}
-	sendURL := fmt.Sprintf("%s/jobgroup/update", x.Options.ServerAddr)
+func (x *xxlApi) updateExecutor(appname, alias, addressList string, id int) error {
+	// https://apaas5.wodcloud.com/xxl-job-admin/jobgroup/update
 	body := url.Values{}
 	body.Add("appname", appname)
 	body.Add("title", alias)
 	body.Add("addressType", "1")
 	body.Add("addressList", addressList)
 	body.Add("id", strconv.Itoa(id))
-	request, _ := http.NewRequest("POST", sendURL, strings.NewReader(body.Encode()))
-	request.Header.Set("cookie", x.cookie)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
+	_, err := x.call("修改执行器", "/jobgroup/update", body)
+	return err
+}
+
+// 检查并添加任务，已有任务只修改声明了的字段
+func (x *xxlApi) checkOrAddJob(executorHandler string, spec JobSpec) error {
+	jobGroup, err := x.executorId()
 	if err != nil {
-		x.log.Error("调用接口【新增执行器】错误：%s", err.Error())
-		return
-	} else {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		// {"code":200,"msg":null,"content":null}
-		if err != nil {
-			x.log.Error("调用接口【新增执行器】返回错误：%s", err.Error())
-			return
+		return err
+	}
+	job, err := x.getJob(jobGroup, executorHandler)
+	if err != nil {
+		return err
+	}
+	if job.ExecutorHandler == "" {
+		job = spec.newJob(executorHandler)
+		job.JobGroup = jobGroup
+		if job.Id, err = x.addJob(job); err != nil {
+			return err
 		}
-		res := wenResCode{}
-		json.Unmarshal(respBody, &res)
-		if res.Code == 200 {
-			return
+		if job.ScheduleType != ScheduleNone {
+			return x.startJob(job.Id) //start job
 		}
-		x.log.Error("调用接口【新增执行器】错误信息：%s", res.Msg)
-		return
+		return nil
 	}
+	unscheduled := job.ScheduleType == ScheduleNone
+	if spec.apply(&job) { //modify it if it is not equal.
+		if err = x.updateJob(job); err != nil {
+			return err
+		}
+	}
+	if unscheduled && job.ScheduleType != ScheduleNone && job.TriggerStatus != 1 {
+		return x.startJob(job.Id) //start job
+	}
+	return nil
 }
 
-// 检查并添加任务
-func (x *xxlApi) checkOrAddJob(jobDesc, scheduleConf, executorHandler string) {
-	job, err := x.getJob(executorHandler)
-	if err != nil {
-		x.log.Error("获取执行器错误:%s", err.Error())
-	} else if job.ExecutorHandler == "" {
-		x.addJob(jobDesc, scheduleConf, executorHandler)
-	} else if job.ExecutorHandler == executorHandler && (job.JobDesc != jobDesc || job.ScheduleConf != scheduleConf) { //modify it if it is not equal.
-		x.updateJob(jobDesc, scheduleConf, executorHandler, job.Id)
+// 任务参数，CRON表达式需要同时提交cronGen_display
+func jobValues(job xxlJob) url.Values {
+	body := url.Values{}
+	body.Add("jobGroup", strconv.Itoa(job.JobGroup))
+	body.Add("jobDesc", job.JobDesc)
+	body.Add("author", job.Author)
+	body.Add("alarmEmail", job.AlarmEmail)
+	body.Add("scheduleType", job.ScheduleType)
+	body.Add("scheduleConf", job.ScheduleConf)
+	if job.ScheduleType == ScheduleCron {
+		body.Add("cronGen_display", job.ScheduleConf)
 	}
-	x.startJob(job.Id, executorHandler) //start job
+	body.Add("misfireStrategy", job.MisfireStrategy)
+	body.Add("executorRouteStrategy", job.ExecutorRouteStrategy)
+	body.Add("executorHandler", job.ExecutorHandler)
+	body.Add("executorParam", job.ExecutorParam)
+	body.Add("executorBlockStrategy", job.ExecutorBlockStrategy)
+	body.Add("executorTimeout", strconv.Itoa(job.ExecutorTimeout))
+	body.Add("executorFailRetryCount", strconv.Itoa(job.ExecutorFailRetryCount))
+	body.Add("glueType", job.GlueType)
+	body.Add("glueRemark", job.GlueRemark)
+	body.Add("childJobId", job.ChildJobId)
+	return body
 }
 
-// 获取任务
-func (x *xxlApi) getJob(executorHandler string) (job xxlJob, err error) {
+// 获取任务，不存在时返回空
+func (x *xxlApi) getJob(jobGroup int, executorHandler string) (job xxlJob, err error) {
 	// https://apaas5.wodcloud.com/xxl-job-admin/jobinfo/pageList
-	if x.cookie == "" {
-		if err := x.login(); err != nil {
-			return job, err
-		}
-	}
-	executor, err := x.getExecutor(x.RegistryKey)
-	if err != nil {
-		x.log.Error("获取执行器Id信息错误:%s", err.Error())
-		return
-	} else if executor.Id == 0 {
-		x.log.Error("获取执行器Id为0")
-		return
-	}
-	sendURL := fmt.Sprintf("%s/jobinfo/pageList", x.Options.ServerAddr)
 	body := url.Values{}
-	body.Add("jobGroup", strconv.Itoa(executor.Id))
+	body.Add("jobGroup", strconv.Itoa(jobGroup))
 	body.Add("executorHandler", executorHandler)
 	body.Add("triggerStatus", "-1")
-	request, _ := http.NewRequest("POST", sendURL, strings.NewReader(body.Encode()))
-	request.Header.Set("cookie", x.cookie)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
-	if err != nil {
+	res := webResJob{}
+	if err = x.post("查询任务", "/jobinfo/pageList", body, &res); err != nil {
 		return job, err
-	} else {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return job, err
-		}
-		res := webResJob{}
-		json.Unmarshal(respBody, &res)
-		for i, v := range res.Data {
-			v.ExecutorHandler = strings.ReplaceAll(v.ExecutorHandler, " ", "")
-			if v.JobGroup == executor.Id && v.ExecutorHandler == executorHandler {
-				job = res.Data[i]
-				return job, nil
-			}
+	}
+	for i, v := range res.Data {
+		v.ExecutorHandler = strings.ReplaceAll(v.ExecutorHandler, " ", "")
+		if v.JobGroup == jobGroup && v.ExecutorHandler == executorHandler {
+			return res.Data[i], nil
 		}
-		return job, err
 	}
+	return job, nil
 }
 
-// 新增任务
-func (x *xxlApi) addJob(jobDesc, scheduleConf, executorHandler string) {
-	// https://apaas5.wodcloud.com/xxl-job-admin/jobinfo/pageList
-	if x.cookie == "" {
-		if err := x.login(); err != nil {
-			return
-		}
-	}
-	executor, err := x.getExecutor(x.RegistryKey)
+// 新增任务，返回任务Id
+func (x *xxlApi) addJob(job xxlJob) (int, error) {
+	// https://apaas5.wodcloud.com/xxl-job-admin/jobinfo/add
+	const op = "新增任务"
+	content, err := x.call(op, "/jobinfo/add", jobValues(job))
 	if err != nil {
-		x.log.Error("获取执行器Id信息错误:%s", err.Error())
-		return
-	} else if executor.Id == 0 {
-		x.log.Error("获取执行器Id为0")
-		return
+		return 0, err
 	}
-	sendURL := fmt.Sprintf("%s/jobinfo/add", x.Options.ServerAddr)
-	body := url.Values{}
-	body.Add("jobGroup", strconv.Itoa(executor.Id))
-	body.Add("jobDesc", jobDesc)
-	body.Add("author", "beagle")
-	body.Add("scheduleType", "CRON")
-	body.Add("scheduleConf", scheduleConf)
-	body.Add("cronGen_display", scheduleConf)
-	body.Add("glueType", "BEAN")
-	body.Add("executorHandler", executorHandler)
-	body.Add("executorRouteStrategy", "FIRST")
-	body.Add("misfireStrategy", "DO_NOTHING")
-	body.Add("executorBlockStrategy", "SERIAL_EXECUTION")
-	body.Add("executorTimeout", "0")
-	body.Add("executorFailRetryCount", "0")
-	body.Add("glueRemark", "GLUE代码初始化")
-	request, _ := http.NewRequest("POST", sendURL, strings.NewReader(body.Encode()))
-	request.Header.Set("cookie", x.cookie)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
+	id, err := strconv.Atoi(content)
 	if err != nil {
-		x.log.Error("调用接口【新增任务】错误：%s", err.Error())
-		return
-	} else {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			x.log.Error("调用接口【新增任务】返回错误：%s", err.Error())
-			return
-		}
-		res := wenResCode{}
-		json.Unmarshal(respBody, &res)
-		if res.Code == 200 {
-			return
-		}
-		x.log.Error("调用接口【新增任务】错误信息：%s", res.Msg)
-		return
+		return 0, &AdminError{Op: op, Err: err}
 	}
+	return id, nil
 }
 
 // 修改任务
-func (x *xxlApi) updateJob(jobDesc, scheduleConf, executorHandler string, id int) {
-	// https://apaas5.wodcloud.com/xxl-job-admin/jobinfo/pageList
-	if x.cookie == "" {
-		if err := x.login(); err != nil {
-			return
-		}
-	}
-	executor, err := x.getExecutor(x.RegistryKey)
-	if err != nil {
-		x.log.Error("获取执行器Id信息错误:%s", err.Error())
-		return
-	} else if executor.Id == 0 {
-		x.log.Error("获取执行器Id为0")
-		return
-	}
-	sendURL := fmt.Sprintf("%s/jobinfo/update", x.Options.ServerAddr)
-	body := url.Values{}
-	body.Add("scheduleConf", scheduleConf)
-	body.Add("jobDesc", jobDesc)
-	body.Add("executorHandler", executorHandler)
-	body.Add("id", strconv.Itoa(id))
-	body.Add("author", "beagle")
-	body.Add("scheduleType", "CRON")
-	body.Add("executorRouteStrategy", "FIRST")
-	body.Add("executorFailRetryCount", "0")
-	body.Add("misfireStrategy", "DO_NOTHING")
-	body.Add("executorBlockStrategy", "SERIAL_EXECUTION")
-	body.Add("jobGroup", strconv.Itoa(executor.Id))
-	body.Add("cronGen_display", scheduleConf)
-	body.Add("glueType", "BEAN")
-	body.Add("executorTimeout", "0")
-	body.Add("triggerStatus", "1")
-	body.Add("glueRemark", "GLUE代码初始化")
-	request, _ := http.NewRequest("POST", sendURL, strings.NewReader(body.Encode()))
-	request.Header.Set("cookie", x.cookie)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
-	if err != nil {
-		x.log.Error("调用接口【修改任务】错误：%s", err.Error())
-		return
-	} else {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			x.log.Error("调用接口【修改任务】返回错误：%s", err.Error())
-			return
-		}
-		res := wenResCode{}
-		json.Unmarshal(respBody, &res)
-		if res.Code == 200 {
-			return
-		}
-		x.log.Error("调用接口【修改任务】错误信息：%s", res.Msg)
-		return
-	}
+func (x *xxlApi) updateJob(job xxlJob) error {
+	// https://apaas5.wodcloud.com/xxl-job-admin/jobinfo/update
+	body := jobValues(job)
+	body.Add("id", strconv.Itoa(job.Id))
+	_, err := x.call("修改任务", "/jobinfo/update", body)
+	return err
 }
 
 // 启动任务
-func (x *xxlApi) startJob(id int, executorHandler string) {
-	if x.cookie == "" {
-		if err := x.login(); err != nil {
-			return
-		}
-	}
-	executor, err := x.getExecutor(x.RegistryKey)
-	if err != nil {
-		x.log.Error("获取执行器Id信息错误:%s", err.Error())
-		return
-	} else if executor.Id == 0 {
-		x.log.Error("获取执行器Id为0")
-		return
-	}
-	sendURL := fmt.Sprintf("%s/jobinfo/start", x.Options.ServerAddr)
+func (x *xxlApi) startJob(id int) error {
+	// https://apaas5.wodcloud.com/xxl-job-admin/jobinfo/start
 	body := url.Values{}
 	body.Add("id", strconv.Itoa(id))
-	request, _ := http.NewRequest("POST", sendURL, strings.NewReader(body.Encode()))
-	request.Header.Set("cookie", x.cookie)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
-	if err != nil {
-		x.log.Error("调用接口【启动任务】错误：%s", err.Error())
-		return
-	} else {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			x.log.Error("调用接口【启动任务】返回错误：%s", err.Error())
-			return
-		}
-		res := wenResCode{}
-		json.Unmarshal(respBody, &res)
-		if res.Code == 200 {
-			return
-		}
-		x.log.Error("调用接口【启动任务】错误信息：%s", res.Msg)
-		return
-	}
+	_, err := x.call("启动任务", "/jobinfo/start", body)
+	return err
 }